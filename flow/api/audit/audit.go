@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+
+	"flow/api/serde"
+	"flow/common/db"
+	"flow/common/util"
+)
+
+const selectProgramQuery = `
+SELECT program FROM "user" WHERE id = $1
+`
+
+// Transfer credits have no term and are always completed
+const selectTakenQuery = `
+SELECT c.code, uct.term_id, uct.earned_units
+FROM user_course_taken uct
+  JOIN course c ON c.id = uct.course_id
+WHERE uct.user_id = $1
+`
+
+type takenRow struct {
+	Code   string
+	TermId *int
+	// Known only for graded courses from transcripts
+	EarnedUnits *float64
+}
+
+// collectTaken merges the rows of courses taken more than once, and returns
+// the courses along with the first term in which any course was taken.
+// Courses of the current term or later are in progress, unless they were also completed before.
+// Courses that earned no units, such as failed or withdrawn ones, do not count at all.
+func collectTaken(rows []takenRow, currentTermId int) ([]takenCourse, int) {
+	firstTermId := 0
+	byCode := make(map[string]*takenCourse)
+	var codes []string
+	for _, row := range rows {
+		if row.TermId != nil && (firstTermId == 0 || *row.TermId < firstTermId) {
+			firstTermId = *row.TermId
+		}
+		if row.EarnedUnits != nil && *row.EarnedUnits == 0 {
+			continue
+		}
+
+		units := courseUnits
+		if row.EarnedUnits != nil {
+			units = *row.EarnedUnits
+		}
+		completed := row.TermId == nil || *row.TermId < currentTermId
+		course, ok := byCode[row.Code]
+		if !ok {
+			byCode[row.Code] = &takenCourse{Code: row.Code, InProgress: !completed, Units: units}
+			codes = append(codes, row.Code)
+			continue
+		}
+		course.InProgress = course.InProgress && !completed
+		if row.EarnedUnits != nil {
+			course.Units = units
+		}
+	}
+
+	courses := make([]takenCourse, 0, len(codes))
+	for _, code := range codes {
+		courses = append(courses, *byCode[code])
+	}
+	return courses, firstTermId
+}
+
+// selectTaken returns the courses taken by the user and the first term they took courses in.
+func selectTaken(tx *db.Tx, userId int) ([]takenCourse, int, error) {
+	rows, err := tx.Query(selectTakenQuery, userId)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying courses taken: %w", err)
+	}
+	defer rows.Close()
+
+	var taken []takenRow
+	for rows.Next() {
+		var row takenRow
+		err = rows.Scan(&row.Code, &row.TermId, &row.EarnedUnits)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reading course taken: %w", err)
+		}
+		taken = append(taken, row)
+	}
+
+	courses, firstTermId := collectTaken(taken, util.CurrentTermId())
+	return courses, firstTermId, nil
+}
+
+// HandleAudit loads the embedded program definitions and returns a handler
+// checking the courses taken by the user against the requirements of their program.
+func HandleAudit() (func(*db.Tx, *http.Request) (interface{}, error), error) {
+	programs, err := loadPrograms(programFiles)
+	if err != nil {
+		return nil, fmt.Errorf("loading program definitions: %w", err)
+	}
+	return func(tx *db.Tx, r *http.Request) (interface{}, error) {
+		return handleAudit(programs, tx, r)
+	}, nil
+}
+
+func handleAudit(programs map[string][]*program, tx *db.Tx, r *http.Request) (interface{}, error) {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	var programName *string
+	err = tx.QueryRow(selectProgramQuery, userId).Scan(&programName)
+	if err != nil {
+		return nil, fmt.Errorf("querying program: %w", err)
+	}
+	// The program is set when a transcript is imported
+	if programName == nil || *programName == "" {
+		return nil, serde.WithStatus(
+			http.StatusNotFound,
+			serde.WithEnum(serde.NoProgram, fmt.Errorf("user %d has no program", userId)),
+		)
+	}
+
+	courses, firstTermId, err := selectTaken(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := findProgram(programs, *programName, firstTermId)
+	if prog == nil {
+		return nil, serde.WithStatus(
+			http.StatusNotFound,
+			serde.WithEnum(serde.UnknownProgram, fmt.Errorf("no requirements for program %s", *programName)),
+		)
+	}
+
+	return audit(prog, courses), nil
+}
@@ -0,0 +1,274 @@
+package audit
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadPrograms(t *testing.T) {
+	// Definitions are embedded, so a broken one is a programming error caught here
+	programs, err := loadPrograms(programFiles)
+	if err != nil {
+		t.Fatalf("loading embedded programs: %v", err)
+	}
+	versions := programs["computer science"]
+	if len(versions) < 2 {
+		t.Fatalf("expected several versions of Computer Science, got %d", len(versions))
+	}
+
+	invalid := []string{
+		`{"name": "X", "version": "1", "requirements": [{"name": "A", "courses": [["CS 135"]]}]}`,
+		`{"name": "X", "version": "1", "requirements": [{"name": "A", "units": 1.0}]}`,
+		`{"name": "X", "version": "1", "requirements": [{"name": "A", "min": 2, "groups": [{"name": "B", "courses": [["cs135"]]}]}]}`,
+		`{"name": "X", "version": "1", "requirements": [{"name": "A", "courses": [["cs135"]], "units": 1.0, "from": ["cs"]}]}`,
+		`{"name": "X", "version": "1", "requirements": [{"name": "A", "course": [["cs135"]]}]}`,
+		`{"name": "X", "requirements": []}`,
+	}
+	for _, definition := range invalid {
+		fsys := fstest.MapFS{"programs/x.json": {Data: []byte(definition)}}
+		if _, err := loadPrograms(fsys); err == nil {
+			t.Errorf("expected an error for %s", definition)
+		}
+	}
+}
+
+func TestFindProgram(t *testing.T) {
+	older := &program{Name: "Computer Science", Version: "old", FirstTermId: 1199}
+	newer := &program{Name: "Computer Science", Version: "new", FirstTermId: 1239}
+	programs := map[string][]*program{"computer science": {older, newer}}
+
+	tests := []struct {
+		name        string
+		program     string
+		firstTermId int
+		want        *program
+	}{
+		{"before_first_version", "Computer Science", 1149, older},
+		{"first_version", "Computer Science", 1229, older},
+		{"second_version", "Computer Science", 1239, newer},
+		{"no_terms", "Computer Science", 0, newer},
+		{"option", "Computer Science/Digital Hardware Option", 1239, newer},
+		{"unknown", "Pure Mathematics", 1239, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findProgram(programs, tt.program, tt.firstTermId)
+			if got != tt.want {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		code    string
+		pattern string
+		want    bool
+	}{
+		{"cs341", "cs", true},
+		{"cs341", "cs34", true},
+		{"cs341", "cs35", false},
+		{"cse101", "cs", false},
+		{"cs", "cs", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesPattern(tt.code, tt.pattern); got != tt.want {
+			t.Errorf("matchesPattern(%q, %q) = %v; want %v", tt.code, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	prog := &program{
+		Name:    "Computer Science",
+		Version: "test",
+		Requirements: []requirement{
+			{
+				Name:    "Core",
+				Courses: [][]string{{"cs135", "cs145"}, {"cs136", "cs146"}},
+			},
+			{
+				Name:    "Communication",
+				Courses: [][]string{{"engl119", "spcom100"}, {"engl119", "spcom223"}},
+			},
+			{
+				Name:  "Upper year",
+				Units: 1.0,
+				From:  []string{"cs3", "cs4"},
+			},
+			{
+				Name: "Breadth",
+				Min:  1,
+				Groups: []requirement{
+					{Name: "Humanities", Units: 0.5, From: []string{"phil"}},
+					{Name: "Sciences", Units: 0.5, From: []string{"phys"}},
+				},
+			},
+		},
+	}
+	units := func(value float64) *float64 { return &value }
+
+	tests := []struct {
+		name    string
+		courses []takenCourse
+		want    auditResponse
+	}{
+		{
+			"outstanding",
+			[]takenCourse{
+				{Code: "cs145", Units: 0.5},
+				{Code: "cs341", Units: 0.5},
+				{Code: "engl119", Units: 0.5},
+				{Code: "phys121", InProgress: true, Units: 0.5},
+			},
+			auditResponse{
+				Program: "Computer Science",
+				Version: "test",
+				Status:  outstanding,
+				Requirements: []requirementResult{
+					{
+						Name:    "Core",
+						Status:  outstanding,
+						Courses: []string{"CS145"},
+						Missing: []string{"CS136 or CS146"},
+					},
+					{
+						Name:    "Communication",
+						Status:  outstanding,
+						Courses: []string{"ENGL119"},
+						Missing: []string{"ENGL119 or SPCOM223"},
+					},
+					{
+						Name:          "Upper year",
+						Status:        outstanding,
+						Courses:       []string{"CS341"},
+						UnitsRequired: units(1.0),
+						UnitsTaken:    units(0.5),
+					},
+					{
+						Name:    "Breadth",
+						Status:  inProgress,
+						Courses: []string{},
+						Groups: []requirementResult{
+							{
+								Name:          "Humanities",
+								Status:        outstanding,
+								Courses:       []string{},
+								UnitsRequired: units(0.5),
+								UnitsTaken:    units(0),
+							},
+							{
+								Name:          "Sciences",
+								Status:        inProgress,
+								Courses:       []string{"PHYS121"},
+								UnitsRequired: units(0.5),
+								UnitsTaken:    units(0.5),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// A course counts towards one requirement only,
+			// and completed courses are used before those in progress.
+			// ENGL119 fits both communication groups, but has to go to the second,
+			// as SPCOM100 only fits the first.
+			"allocation",
+			[]takenCourse{
+				{Code: "cs135", InProgress: true, Units: 0.5},
+				{Code: "cs145", Units: 0.5},
+				{Code: "cs146", Units: 0.5},
+				{Code: "cs341", Units: 0.5},
+				{Code: "cs350", Units: 0.5},
+				{Code: "cs451", InProgress: true, Units: 0.5},
+				{Code: "engl119", Units: 0.5},
+				{Code: "phil145", Units: 0.5},
+				{Code: "spcom100", Units: 0.5},
+			},
+			auditResponse{
+				Program: "Computer Science",
+				Version: "test",
+				Status:  satisfied,
+				Requirements: []requirementResult{
+					{Name: "Core", Status: satisfied, Courses: []string{"CS145", "CS146"}},
+					{Name: "Communication", Status: satisfied, Courses: []string{"SPCOM100", "ENGL119"}},
+					{
+						Name:          "Upper year",
+						Status:        satisfied,
+						Courses:       []string{"CS341", "CS350"},
+						UnitsRequired: units(1.0),
+						UnitsTaken:    units(1.0),
+					},
+					{
+						Name:    "Breadth",
+						Status:  satisfied,
+						Courses: []string{},
+						Groups: []requirementResult{
+							{
+								Name:          "Humanities",
+								Status:        satisfied,
+								Courses:       []string{"PHIL145"},
+								UnitsRequired: units(0.5),
+								UnitsTaken:    units(0.5),
+							},
+							{
+								Name:          "Sciences",
+								Status:        outstanding,
+								Courses:       []string{},
+								UnitsRequired: units(0.5),
+								UnitsTaken:    units(0),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := audit(prog, tt.courses)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestCollectTaken(t *testing.T) {
+	term := func(id int) *int { return &id }
+	units := func(value float64) *float64 { return &value }
+	rows := []takenRow{
+		// Failed, then passed
+		{Code: "cs135", TermId: term(1219), EarnedUnits: units(0)},
+		{Code: "cs135", TermId: term(1221), EarnedUnits: units(0.5)},
+		// Withdrawn, so it does not count even though it is in the first term
+		{Code: "math135", TermId: term(1189), EarnedUnits: units(0)},
+		// Transfer credit
+		{Code: "chem120", EarnedUnits: units(0.5)},
+		// Imported from a schedule
+		{Code: "cs136", TermId: term(1249)},
+		// Worth a quarter of a unit
+		{Code: "pd1", TermId: term(1225), EarnedUnits: units(0.25)},
+	}
+
+	courses, firstTermId := collectTaken(rows, 1249)
+	want := []takenCourse{
+		{Code: "cs135", Units: 0.5},
+		{Code: "chem120", Units: 0.5},
+		{Code: "cs136", InProgress: true, Units: 0.5},
+		{Code: "pd1", Units: 0.25},
+	}
+	if !cmp.Equal(want, courses) {
+		t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, courses))
+	}
+	if firstTermId != 1189 {
+		t.Errorf("got first term %d; want 1189", firstTermId)
+	}
+}
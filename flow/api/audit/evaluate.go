@@ -0,0 +1,265 @@
+package audit
+
+import (
+	"sort"
+	"strings"
+)
+
+// Most courses are worth half a unit. Units are only known for graded courses
+// imported from transcripts, so this is assumed for the others.
+const courseUnits = 0.5
+
+// Statuses of requirements
+const (
+	satisfied = "satisfied"
+	// Satisfied once the courses of the current term are completed
+	inProgress  = "in_progress"
+	outstanding = "outstanding"
+)
+
+type requirementResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Courses that count towards the requirement, e.g. CS135
+	Courses []string `json:"courses"`
+	// Groups of courses that are still to be taken, e.g. "CS240 or CS240E"
+	Missing []string `json:"missing,omitempty"`
+	// Set only for unit requirements
+	UnitsRequired *float64 `json:"units_required,omitempty"`
+	UnitsTaken    *float64 `json:"units_taken,omitempty"`
+	// Set only for requirements made of groups
+	Groups []requirementResult `json:"groups,omitempty"`
+}
+
+type takenCourse struct {
+	Code       string
+	InProgress bool
+	Units      float64
+}
+
+// allocator hands out courses taken to requirements. Each course counts towards
+// the first requirement that uses it, in the order of the definition,
+// which is why more specific requirements should come first.
+type allocator struct {
+	// Completed courses come before those in progress,
+	// so that requirements are satisfied by completed courses when possible.
+	courses []takenCourse
+	used    []bool
+}
+
+func newAllocator(courses []takenCourse) *allocator {
+	sorted := make([]takenCourse, len(courses))
+	copy(sorted, courses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].InProgress != sorted[j].InProgress {
+			return !sorted[i].InProgress
+		}
+		return sorted[i].Code < sorted[j].Code
+	})
+	return &allocator{courses: sorted, used: make([]bool, len(sorted))}
+}
+
+// take marks the first unused course satisfying match as used and returns it
+func (a *allocator) take(match func(code string) bool) (takenCourse, bool) {
+	for i, course := range a.courses {
+		if !a.used[i] && match(course.Code) {
+			a.used[i] = true
+			return course, true
+		}
+	}
+	return takenCourse{}, false
+}
+
+// matching assigns a distinct course to each group of alternatives, e.g. CS136 or CS146,
+// finding augmenting paths so that taking one course for a group does not leave
+// a later group without a course that another one could have been given instead.
+type matching struct {
+	a      *allocator
+	groups [][]string
+	// Index of the course given to each group, or -1
+	assigned []int
+	// Group to which each course is given, or -1
+	owner   []int
+	visited []bool
+	// Whether courses in progress may be given to groups
+	inProgress bool
+}
+
+func inGroup(code string, group []string) bool {
+	for _, alternative := range group {
+		if code == alternative {
+			return true
+		}
+	}
+	return false
+}
+
+// augment tries to give a course to group g, moving other groups to other courses if needed
+func (m *matching) augment(g int) bool {
+	for i, course := range m.a.courses {
+		if m.a.used[i] || m.visited[i] || (course.InProgress && !m.inProgress) {
+			continue
+		}
+		if !inGroup(course.Code, m.groups[g]) {
+			continue
+		}
+		m.visited[i] = true
+		if m.owner[i] == -1 || m.augment(m.owner[i]) {
+			m.assigned[g] = i
+			m.owner[i] = g
+			return true
+		}
+	}
+	return false
+}
+
+// takeGroups gives the most groups possible a distinct unused course each, marks these as used
+// and returns the index of the course of each group, or -1 for groups left without one.
+// Completed courses are matched first, and augmenting paths never leave a matched course
+// unmatched, so courses in progress are only used where completed ones do not suffice.
+func (a *allocator) takeGroups(groups [][]string) []int {
+	m := matching{
+		a:        a,
+		groups:   groups,
+		assigned: make([]int, len(groups)),
+		owner:    make([]int, len(a.courses)),
+	}
+	for i := range m.assigned {
+		m.assigned[i] = -1
+	}
+	for i := range m.owner {
+		m.owner[i] = -1
+	}
+
+	for _, inProgress := range []bool{false, true} {
+		m.inProgress = inProgress
+		for g := range groups {
+			if m.assigned[g] == -1 {
+				m.visited = make([]bool, len(a.courses))
+				m.augment(g)
+			}
+		}
+	}
+
+	for _, i := range m.assigned {
+		if i != -1 {
+			a.used[i] = true
+		}
+	}
+	return m.assigned
+}
+
+// matchesPattern checks a course code against a pattern such as cs or cs34
+func matchesPattern(code, pattern string) bool {
+	subject := strings.TrimRight(pattern, "0123456789")
+	if !strings.HasPrefix(code, pattern) || len(code) == len(subject) {
+		return false
+	}
+	// cs must not match cse101: the subject has to end where the number starts
+	next := code[len(subject)]
+	return next >= '0' && next <= '9'
+}
+
+func matchesAny(code string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(code, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *allocator) evaluate(req *requirement) requirementResult {
+	result := requirementResult{Name: req.Name, Courses: []string{}}
+	met, anyInProgress := true, false
+	use := func(course takenCourse) {
+		result.Courses = append(result.Courses, strings.ToUpper(course.Code))
+		anyInProgress = anyInProgress || course.InProgress
+	}
+
+	switch {
+	case len(req.Courses) > 0:
+		for g, i := range a.takeGroups(req.Courses) {
+			if i != -1 {
+				use(a.courses[i])
+			} else {
+				met = false
+				codes := make([]string, len(req.Courses[g]))
+				for j, code := range req.Courses[g] {
+					codes[j] = strings.ToUpper(code)
+				}
+				result.Missing = append(result.Missing, strings.Join(codes, " or "))
+			}
+		}
+
+	case req.Units > 0:
+		taken := 0.0
+		for taken < req.Units {
+			course, ok := a.take(func(code string) bool {
+				return matchesAny(code, req.From) && !matchesAny(code, req.Except)
+			})
+			if !ok {
+				break
+			}
+			use(course)
+			taken += course.Units
+		}
+		required := req.Units
+		met = taken >= required
+		result.UnitsRequired = &required
+		result.UnitsTaken = &taken
+
+	default:
+		metGroups, satisfiedGroups := 0, 0
+		for i := range req.Groups {
+			group := a.evaluate(&req.Groups[i])
+			if group.Status != outstanding {
+				metGroups++
+			}
+			if group.Status == satisfied {
+				satisfiedGroups++
+			}
+			result.Groups = append(result.Groups, group)
+		}
+		met = metGroups >= req.Min
+		anyInProgress = satisfiedGroups < req.Min
+	}
+
+	switch {
+	case !met:
+		result.Status = outstanding
+	case anyInProgress:
+		result.Status = inProgress
+	default:
+		result.Status = satisfied
+	}
+	return result
+}
+
+type auditResponse struct {
+	Program string `json:"program"`
+	Version string `json:"version"`
+	// Overall status: the worst status of any requirement
+	Status       string              `json:"status"`
+	Requirements []requirementResult `json:"requirements"`
+}
+
+// audit evaluates the requirements of a program against the courses taken
+func audit(prog *program, courses []takenCourse) auditResponse {
+	response := auditResponse{
+		Program:      prog.Name,
+		Version:      prog.Version,
+		Status:       satisfied,
+		Requirements: []requirementResult{},
+	}
+
+	allocator := newAllocator(courses)
+	for i := range prog.Requirements {
+		result := allocator.evaluate(&prog.Requirements[i])
+		response.Requirements = append(response.Requirements, result)
+		if result.Status == outstanding || response.Status == satisfied {
+			response.Status = result.Status
+		}
+	}
+	return response
+}
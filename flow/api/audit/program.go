@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Requirement definitions are maintained by hand from the undergraduate calendar.
+// There is one file per program and calendar version.
+//
+//go:embed programs/*.json
+var programFiles embed.FS
+
+// program is the definition of the requirements of a program in one version of the calendar
+type program struct {
+	// Name as it appears on transcripts, e.g. "Computer Science"
+	Name string `json:"name"`
+	// Academic year of the calendar, e.g. "2023-2024"
+	Version string `json:"version"`
+	// Students who started in this term or later follow this version
+	FirstTermId  int           `json:"first_term_id"`
+	Requirements []requirement `json:"requirements"`
+}
+
+// requirement is one of three kinds, depending on which fields are set
+type requirement struct {
+	Name string `json:"name"`
+	// Groups of courses that must all be taken, where any course of a group will do
+	Courses [][]string `json:"courses,omitempty"`
+	// Units to be taken from courses matching any of From, but none of Except.
+	// Patterns are a subject optionally followed by the start of the number,
+	// e.g. cs matches all CS courses and cs34 matches CS 340 to CS 349.
+	Units  float64  `json:"units,omitempty"`
+	From   []string `json:"from,omitempty"`
+	Except []string `json:"except,omitempty"`
+	// At least Min of Groups must be satisfied, as for breadth requirements
+	Min    int           `json:"min,omitempty"`
+	Groups []requirement `json:"groups,omitempty"`
+}
+
+var (
+	codeRegexp    = regexp.MustCompile(`^[a-z]{2,}[0-9]{3}[a-z]*$`)
+	patternRegexp = regexp.MustCompile(`^[a-z]{2,}[0-9]{0,3}$`)
+)
+
+func (req *requirement) validate() error {
+	if req.Name == "" {
+		return fmt.Errorf("requirement without name")
+	}
+
+	kinds := 0
+	if len(req.Courses) > 0 {
+		kinds++
+		for _, group := range req.Courses {
+			if len(group) == 0 {
+				return fmt.Errorf("%s: empty course group", req.Name)
+			}
+			for _, code := range group {
+				if !codeRegexp.MatchString(code) {
+					return fmt.Errorf("%s: invalid course code %q", req.Name, code)
+				}
+			}
+		}
+	}
+	if req.Units != 0 {
+		kinds++
+		if req.Units < 0 || len(req.From) == 0 {
+			return fmt.Errorf("%s: units must be positive and taken from some courses", req.Name)
+		}
+		for _, pattern := range append(req.From, req.Except...) {
+			if !patternRegexp.MatchString(pattern) {
+				return fmt.Errorf("%s: invalid course pattern %q", req.Name, pattern)
+			}
+		}
+	}
+	if len(req.Groups) > 0 {
+		kinds++
+		if req.Min < 1 || req.Min > len(req.Groups) {
+			return fmt.Errorf("%s: min must be between 1 and the number of groups", req.Name)
+		}
+		for i := range req.Groups {
+			if err := req.Groups[i].validate(); err != nil {
+				return fmt.Errorf("%s: %w", req.Name, err)
+			}
+		}
+	}
+
+	if kinds != 1 {
+		return fmt.Errorf("%s: exactly one of courses, units or groups must be set", req.Name)
+	}
+	return nil
+}
+
+// loadPrograms reads all definitions and groups their versions by program name in lowercase.
+// Versions of each program are ordered by their first term.
+func loadPrograms(fsys fs.FS) (map[string][]*program, error) {
+	paths, err := fs.Glob(fsys, "programs/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("listing programs: %w", err)
+	}
+
+	programs := make(map[string][]*program)
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		var prog program
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// Misspelled fields would otherwise silently drop requirements
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&prog); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", path, err)
+		}
+		if prog.Name == "" || prog.Version == "" {
+			return nil, fmt.Errorf("%s: name and version are required", path)
+		}
+		for i := range prog.Requirements {
+			if err := prog.Requirements[i].validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		key := strings.ToLower(prog.Name)
+		programs[key] = append(programs[key], &prog)
+	}
+
+	for _, versions := range programs {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].FirstTermId < versions[j].FirstTermId
+		})
+	}
+	return programs, nil
+}
+
+// findProgram returns the version of the user's program that applies to students
+// who started in firstTermId, or nil if there is no definition for the program.
+// Options and specializations, e.g. "Computer Science/Digital Hardware Option",
+// fall back to the definition of the plain program.
+func findProgram(programs map[string][]*program, name string, firstTermId int) *program {
+	name = strings.ToLower(strings.TrimSpace(name))
+	versions, ok := programs[name]
+	if !ok {
+		plain, _, _ := strings.Cut(name, "/")
+		versions, ok = programs[strings.TrimSpace(plain)]
+	}
+	if !ok {
+		return nil
+	}
+
+	// Without any terms taken, the student will follow the latest calendar
+	if firstTermId == 0 {
+		return versions[len(versions)-1]
+	}
+	// Students who started before the oldest definition get the oldest one
+	found := versions[0]
+	for _, version := range versions {
+		if version.FirstTermId <= firstTermId {
+			found = version
+		}
+	}
+	return found
+}
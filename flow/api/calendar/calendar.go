@@ -3,22 +3,29 @@
 package calendar
 
 import (
+	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"flow/api/serde"
 	"flow/common/db"
+	"flow/common/util"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 type postgresEvent struct {
 	SectionId    int
 	CourseCode   string
+	CourseName   string
 	SectionName  string
+	TermId       int
 	Location     *string
 	StartDate    time.Time
 	EndDate      time.Time
@@ -29,114 +36,229 @@ type postgresEvent struct {
 	HasDay [7]bool
 }
 
+// postgresExam is a final exam of a section in the user's schedule.
+// Only exams with a known date and time are extracted.
+type postgresExam struct {
+	SectionId    int
+	CourseCode   string
+	CourseName   string
+	TermId       int
+	Location     *string
+	Date         time.Time
+	StartSeconds int
+	EndSeconds   int
+}
+
 type webcalEvent struct {
-	// An identifier such that (groupid, timestamp) is globally unique.
+	// An identifier such that (groupid, tag) is globally unique.
 	// For example, section ids have this property.
-	GroupId   int
+	GroupId int
+	// Distinguishes events within a group. For meetings, this is the start timestamp.
+	// For exams, it is fixed, so that rescheduled exams are updated in place.
+	Tag       string
 	Summary   string
 	StartTime time.Time
 	EndTime   time.Time
 	Location  string
+	// Start time of the last occurrence of a weekly event, or zero if the event does not recur.
+	Until time.Time
+	// Weekdays on which the event recurs, as given by indexToByDay.
+	Days []string
+	// Start times of otherwise recurring occurrences which do not take place.
+	ExDates []time.Time
+	// If positive, a reminder is given this many minutes before the event.
+	AlarmMinutes int
 }
 
 // 3.3.5 DATE-TIME
 // UTC timestamp format is YYYYMMDD | "T" | HHmmss | "Z"
+// Local timestamp format is the same without the trailing "Z"
 const (
-	dbDateFormat       = "2006-01-02"
-	icsTimestampFormat = "20060102T150405Z"
+	dbDateFormat            = "2006-01-02"
+	icsTimestampFormat      = "20060102T150405Z"
+	icsLocalTimestampFormat = "20060102T150405"
 )
 
-// 3.1 CONTENT LINES
-//   - delimited by a line break, which is a CRLF sequence
-//   - CRLF followed immediately by a single linear white-space character is ignored
-//     (therefore, we must take care to never have a leading space on a line)
-const webcalPreamble = ("BEGIN:VCALENDAR\r\n" +
-	// 3.7.3 PRODID: TEXT
-	// - MUST be specified once in an iCalendar object
-	// - vendor [...] SHOULD assure that this is a globally unique identifier
+// 3.8.5.3 RECURRENCE RULE: RECUR
+// - defines a rule or repeating pattern for recurring events
+// - with local time "DTSTART", the UNTIL rule part MUST be specified as a date with UTC time
+// - The UNTIL rule part defines a [...] value that bounds the recurrence rule in an inclusive manner
+//
+// Each meeting recurs weekly on its days, so we use FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=$LAST_START
+const webcalRecurrenceTemplate = "FREQ=WEEKLY;BYDAY=%s;UNTIL=%s"
+
+var (
+	dayToIndex = map[string]int{
+		"Su": 0,
+		"M":  1,
+		"T":  2,
+		"W":  3,
+		"Th": 4,
+		"F":  5,
+		"S":  6,
+	}
+	// 3.3.10 RECURRENCE RULE: weekday = "SU" / "MO" / "TU" / "WE" / "TH" / "FR" / "SA"
+	indexToByDay = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+)
+
+func writeRecurrence(enc *encoder, event *webcalEvent) {
+	if event.Until.IsZero() {
+		return
+	}
+
+	enc.property("RRULE", fmt.Sprintf(
+		webcalRecurrenceTemplate,
+		strings.Join(event.Days, ","), event.Until.UTC().Format(icsTimestampFormat),
+	))
+	// 3.8.5.1 EXCEPTION DATE-TIMES: DATE-TIME
+	// - defines the list of DATE-TIME exceptions for recurring events
+	// - [its] value type MUST be the same value type that is used to specify [...] "DTSTART"
 	//
-	// We take it to be -//uwflow.com//$SECRET_ID//EN accoring to convention.
-	"PRODID:-//uwflow.com//%s//EN\r\n" +
-	// 3.7.4 VERSION: TEXT
-	// - MUST be specified once in an iCalendar object
-	// - A value of "2.0" corresponds to [RFC 5545]
-	"VERSION:2.0\r\n" +
-	// The following are non-standard (3.8.8.2) properties recognized by Google Calendar
-	"X-WR-CALDESC:Schedule exported from https://uwflow.com\r\n" +
-	"X-WR-CALNAME:UW Flow schedule\r\n")
+	// These are the occurrences falling on holidays and reading breaks.
+	if len(event.ExDates) > 0 {
+		exDates := make([]string, len(event.ExDates))
+		for i, exDate := range event.ExDates {
+			exDates[i] = formatLocal(exDate)
+		}
+		enc.property(
+			withParam("EXDATE", "TZID", util.UniversityLocation.String()),
+			strings.Join(exDates, ","),
+		)
+	}
+}
+
+// 3.6.6 Alarm Component
+// - "ACTION", "TRIGGER" [and for DISPLAY,] "DESCRIPTION" [...] MUST be included
+// - [an alarm within a recurring event] is triggered for each instance of the event
+func writeAlarm(enc *encoder, event *webcalEvent) {
+	if event.AlarmMinutes <= 0 {
+		return
+	}
+
+	enc.begin("VALARM")
+	// 3.8.6.1 ACTION: TEXT
+	// - DISPLAY [is one of the] actions [...] defined by this specification
+	enc.property("ACTION", "DISPLAY")
+	// 3.8.6.3 TRIGGER: DURATION
+	// - [a negative duration] specifies a trigger before the start of the [event]
+	enc.property("TRIGGER", fmt.Sprintf("-PT%dM", event.AlarmMinutes))
+	// 3.8.1.5 DESCRIPTION: TEXT
+	// - contains the text to be displayed when the alarm is triggered
+	enc.text("DESCRIPTION", event.Summary)
+	enc.end("VALARM")
+}
+
+// formatLocal formats a time as local time in util.UniversityLocation.
+func formatLocal(t time.Time) string {
+	return t.In(util.UniversityLocation).Format(icsLocalTimestampFormat)
+}
+
+// eventRange returns the earliest start and the latest end of any occurrence of the events.
+func eventRange(events []*webcalEvent) (time.Time, time.Time) {
+	var from, to time.Time
+	for i, event := range events {
+		end := event.EndTime
+		if !event.Until.IsZero() {
+			end = event.Until.Add(event.EndTime.Sub(event.StartTime))
+		}
+		if i == 0 || event.StartTime.Before(from) {
+			from = event.StartTime
+		}
+		if i == 0 || end.After(to) {
+			to = end
+		}
+	}
+	return from, to
+}
+
+func writeEvent(enc *encoder, feedId string, createTime time.Time, event *webcalEvent) {
+	tzid := util.UniversityLocation.String()
 
-const webcalEventTemplate = ("BEGIN:VEVENT\r\n" +
+	enc.begin("VEVENT")
 	// 3.8.1.12 SUMMARY: TEXT
 	// - CAN be specified [...] to capture a short, one-line summary about the activity
 	//
 	// We use strings of the form "ECE105 - LEC 001" or "ECE105 - FINAL"
-	"SUMMARY:%s\r\n" +
+	enc.text("SUMMARY", event.Summary)
 	// 3.8.4.7 UNIQUE IDENTIFIER: TEXT
 	// - MUST be specified in the "VEVENT" [...] [component]
 	// - MUST be a globally unique identifier
 	//
-	// We use -//uwflow.com//$SECRET_ID//$SECTION_ID//$DTSTART//EN
-	"UID:-//uwflow.com//%s//%04d//%d//EN\r\n" +
+	// We use -//uwflow.com//$FEED_ID//$SECTION_ID//$DTSTART//EN for meetings
+	// and -//uwflow.com//$FEED_ID//$SECTION_ID//FINAL//EN for exams
+	enc.text("UID", fmt.Sprintf("-//uwflow.com//%s//%04d//%s//EN", feedId, event.GroupId, event.Tag))
 	// 3.8.2.4 DTSTART: DATE-TIME
 	// - defines the start date and time for the event
-	"DTSTART:%s\r\n" +
+	enc.property(withParam("DTSTART", "TZID", tzid), formatLocal(event.StartTime))
 	// 3.8.2.2 DTEND: DATE-TIME
 	// - defines the end date and time for the event
 	// - MUST be later in time than the value of the "DTSTART" property
 	// - MUST be specified as a date with local time
 	//   if and only if the "DTSTART" property is also specified as a date with local time
 	//
-	// We can use local time (with explicit TZID=Canada/Eastern), UTC time or floating time
+	// We can use local time (with explicit TZID), UTC time or floating time
 	// ("picture of a clock", like TIMEZONE WITHOUT TIMESTAMP in SQL).
-	// We use UTC time in both cases: Google Calendar unfortunately treats floating as UTC,
-	// and local time is complex, seemingly requiring a handcrafted VTIMEZONE entity.
-	"DTEND:%s\r\n" +
+	// Google Calendar unfortunately treats floating as UTC, and UTC recurrences
+	// drift by an hour across DST, so we use local time in util.UniversityLocation.
+	// The TZID refers to the VTIMEZONE component written by writeTimezone.
+	enc.property(withParam("DTEND", "TZID", tzid), formatLocal(event.EndTime))
 	// 3.8.7.2 DTSTAMP: DATE-TIME
 	// - specifies the date and time that the instance of the iCalendar object was created
 	//
 	// Unlike the previous timestamps, this one is given in UTC time.
 	// This is because we don't want to bother with the server timezone.
-	"DTSTAMP:%s\r\n" +
+	// We use the time at which the contents of the feed last changed,
+	// so that the feed is byte-for-byte identical as long as its ETag is.
+	enc.property("DTSTAMP", createTime.UTC().Format(icsTimestampFormat))
 	// 3.8.1.7 LOCATION: TEXT
 	// - defines the intended venue for the activity defined by a calendar component
 	//
 	// This is the building-room location of a meeting, e.g. "MC 4085"
-	"LOCATION:%s\r\n" +
-	"END:VEVENT\r\n")
-
-var (
-	dayToIndex = map[string]int{
-		"Su": 0,
-		"M":  1,
-		"T":  2,
-		"W":  3,
-		"Th": 4,
-		"F":  5,
-		"S":  6,
-	}
-)
+	enc.text("LOCATION", event.Location)
+	writeRecurrence(enc, event)
+	writeAlarm(enc, event)
+	enc.end("VEVENT")
+}
 
-func writeCalendar(w io.Writer, secretId string, events []*webcalEvent) {
-	createTime := time.Now()
+// writeCalendar writes the events as an iCalendar object.
+// The feedId identifies the feed: it is the secret id for personal feeds.
+// The createTime is used as the DTSTAMP of every event.
+func writeCalendar(w io.Writer, feedId string, events []*webcalEvent, createTime time.Time) {
+	enc := newEncoder(w)
 
-	fmt.Fprintf(w, webcalPreamble, secretId)
+	enc.begin("VCALENDAR")
+	// 3.7.3 PRODID: TEXT
+	// - MUST be specified once in an iCalendar object
+	// - vendor [...] SHOULD assure that this is a globally unique identifier
+	//
+	// We take it to be -//uwflow.com//$FEED_ID//EN accoring to convention.
+	enc.text("PRODID", fmt.Sprintf("-//uwflow.com//%s//EN", feedId))
+	// 3.7.4 VERSION: TEXT
+	// - MUST be specified once in an iCalendar object
+	// - A value of "2.0" corresponds to [RFC 5545]
+	enc.text("VERSION", "2.0")
+	// The following are non-standard (3.8.8.2) properties recognized by Google Calendar
+	enc.text("X-WR-CALDESC", "Schedule exported from https://uwflow.com")
+	enc.text("X-WR-CALNAME", "UW Flow schedule")
+
+	// 3.6.5 Time Zone Component
+	// - MUST be present if the iCalendar object contains [a] property
+	//   that specifies a TZID parameter
+	if len(events) > 0 {
+		from, to := eventRange(events)
+		writeTimezone(enc, util.UniversityLocation, from, to)
+	}
 
-	createTimeString := createTime.UTC().Format(icsTimestampFormat)
 	for _, event := range events {
-		startTimeString := event.StartTime.Format(icsTimestampFormat)
-		endTimeString := event.EndTime.Format(icsTimestampFormat)
-		fmt.Fprintf(
-			w, webcalEventTemplate,
-			event.Summary, secretId, event.GroupId, event.StartTime.Unix(),
-			startTimeString, endTimeString, createTimeString, event.Location,
-		)
+		writeEvent(enc, feedId, createTime, event)
 	}
-	io.WriteString(w, "END:VCALENDAR\r\n")
+	enc.end("VCALENDAR")
 }
 
 const selectEventQuery = `
 SELECT
-  sm.section_id, c.code, cs.section_name, COALESCE(NULLIF(us.location, ''), sm.location),
+  sm.section_id, c.code, c.name, cs.section_name, cs.term_id,
+  COALESCE(NULLIF(us.location, ''), sm.location),
   sm.start_date :: TEXT, sm.end_date :: TEXT,
   sm.start_seconds, sm.end_seconds, sm.days
 FROM
@@ -151,18 +273,30 @@ WHERE us.user_id = $1
   AND sm.end_seconds IS NOT NULL
 `
 
-const userIdQuery = `SELECT id FROM "user" WHERE secret_id = $1`
-
-func extractUserEvents(conn *db.Conn, secretId string) ([]*postgresEvent, error) {
-	var events []*postgresEvent
+const userIdQuery = `SELECT id, calendar_enabled FROM "user" WHERE secret_id = $1`
 
+func extractUserId(conn *db.Conn, secretId string) (int, error) {
 	var userId int
-	err := conn.QueryRow(userIdQuery, secretId).Scan(&userId)
+	var enabled bool
+	err := conn.QueryRow(userIdQuery, secretId).Scan(&userId, &enabled)
 	if err != nil {
-		return nil, fmt.Errorf("no user with secret id %s", secretId)
+		return 0, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("no user with secret id %s", secretId))
+	}
+	if !enabled {
+		return 0, serde.WithStatus(http.StatusNotFound, fmt.Errorf("feed of user %d is disabled", userId))
 	}
+	return userId, nil
+}
+
+func extractUserEvents(conn *db.Conn, userId int) ([]*postgresEvent, error) {
+	return extractEvents(conn, selectEventQuery, userId)
+}
 
-	rows, err := conn.Query(selectEventQuery, userId)
+// extractEvents runs a query with the same columns as selectEventQuery.
+func extractEvents(conn *db.Conn, query string, args ...interface{}) ([]*postgresEvent, error) {
+	var events []*postgresEvent
+
+	rows, err := conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying events: %w", err)
 	}
@@ -173,7 +307,7 @@ func extractUserEvents(conn *db.Conn, secretId string) ([]*postgresEvent, error)
 		var startDateStr, endDateStr string
 
 		err = rows.Scan(
-			&ev.SectionId, &ev.CourseCode, &ev.SectionName, &ev.Location,
+			&ev.SectionId, &ev.CourseCode, &ev.CourseName, &ev.SectionName, &ev.TermId, &ev.Location,
 			&startDateStr, &endDateStr, &ev.StartSeconds, &ev.EndSeconds, &ev.Days,
 		)
 		if err != nil {
@@ -183,8 +317,8 @@ func extractUserEvents(conn *db.Conn, secretId string) ([]*postgresEvent, error)
 		// Instead of parsing directly into time.Time, we go through strings
 		// to specify the right timezone. This sounds slow, but it's better
 		// than fixing up after each postgresEvent gets exploded into many webcalEvents.
-		ev.StartDate, _ = time.ParseInLocation(dbDateFormat, startDateStr, UniversityLocation)
-		ev.EndDate, _ = time.ParseInLocation(dbDateFormat, endDateStr, UniversityLocation)
+		ev.StartDate, _ = time.ParseInLocation(dbDateFormat, startDateStr, util.UniversityLocation)
+		ev.EndDate, _ = time.ParseInLocation(dbDateFormat, endDateStr, util.UniversityLocation)
 
 		for _, day := range ev.Days {
 			ev.HasDay[dayToIndex[day]] = true
@@ -196,7 +330,148 @@ func extractUserEvents(conn *db.Conn, secretId string) ([]*postgresEvent, error)
 	return events, nil
 }
 
-func postgresToWebcalEvent(event *postgresEvent, date time.Time) *webcalEvent {
+const selectExamQuery = `
+SELECT
+  se.section_id, c.code, c.name, cs.term_id, se.location,
+  se.date :: TEXT, se.start_seconds, se.end_seconds
+FROM
+  user_schedule us
+  JOIN course_section cs ON cs.id = us.section_id
+  JOIN section_exam se ON se.section_id = us.section_id
+  JOIN course c ON c.id = cs.course_id
+WHERE us.user_id = $1
+  -- Exams that are yet to be scheduled cannot be placed on a calendar.
+  AND NOT se.is_tba
+  AND se.date IS NOT NULL
+  AND se.start_seconds IS NOT NULL
+  AND se.end_seconds IS NOT NULL
+`
+
+func extractUserExams(conn *db.Conn, userId int) ([]*postgresExam, error) {
+	return extractExams(conn, selectExamQuery, userId)
+}
+
+// extractExams runs a query with the same columns as selectExamQuery.
+func extractExams(conn *db.Conn, query string, args ...interface{}) ([]*postgresExam, error) {
+	var exams []*postgresExam
+
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying exams: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ex postgresExam
+		var dateStr string
+
+		err = rows.Scan(
+			&ex.SectionId, &ex.CourseCode, &ex.CourseName, &ex.TermId, &ex.Location,
+			&dateStr, &ex.StartSeconds, &ex.EndSeconds,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("reading exam row: %w", err)
+		}
+
+		ex.Date, _ = time.ParseInLocation(dbDateFormat, dateStr, util.UniversityLocation)
+		exams = append(exams, &ex)
+	}
+
+	return exams, nil
+}
+
+const selectHolidayQuery = `SELECT date :: TEXT FROM holiday`
+
+// extractHolidays returns the set of dates without classes, formatted as dbDateFormat.
+func extractHolidays(conn *db.Conn) (map[string]bool, error) {
+	holidays := make(map[string]bool)
+
+	rows, err := conn.Query(selectHolidayQuery)
+	if err != nil {
+		return nil, fmt.Errorf("querying holidays: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+		err = rows.Scan(&date)
+		if err != nil {
+			return nil, fmt.Errorf("reading holiday row: %w", err)
+		}
+		holidays[date] = true
+	}
+
+	return holidays, nil
+}
+
+// etagVersion is mixed into every digest. It should be bumped whenever
+// the output of writeCalendar changes for the same data, so that clients refetch.
+const etagVersion = 1
+
+// The digest of a feed covers exactly the rows that it is generated from.
+// Computing it is much cheaper than generating the feed, as no rows leave the database,
+// but it still reads all meetings and exams of the user and all holidays on every poll.
+// These are few, and none of them has a modification time that could stand in for it,
+// nor would one reveal deleted rows.
+var selectEtagQuery = fmt.Sprintf(`
+SELECT md5(concat_ws('|',
+  (SELECT string_agg(e :: TEXT, ',' ORDER BY e :: TEXT) FROM (%s) e),
+  (SELECT string_agg(x :: TEXT, ',' ORDER BY x :: TEXT) FROM (%s) x),
+  (SELECT string_agg(h :: TEXT, ',' ORDER BY h :: TEXT) FROM holiday h)
+))
+`, selectEventQuery, selectExamQuery)
+
+func extractDigest(conn *db.Conn, userId int) (string, error) {
+	var digest string
+	err := conn.QueryRow(selectEtagQuery, userId).Scan(&digest)
+	if err != nil {
+		return "", fmt.Errorf("computing digest: %w", err)
+	}
+	return fmt.Sprintf("%d-%s", etagVersion, digest), nil
+}
+
+// makeEtag combines the digest of the data with the options that the feed was requested with.
+// Only the digest is stored in user_calendar: otherwise, clients subscribed
+// to the same schedule with different options would keep bumping each other's modification time.
+func makeEtag(digest string, options *calendarOptions) string {
+	return fmt.Sprintf(`"%s-%x"`, digest, md5.Sum([]byte(options.String())))
+}
+
+// The etag column holds the digest, without the options of any request.
+const selectModifiedQuery = `
+SELECT etag, modified_at FROM user_calendar WHERE user_id = $1
+`
+
+const upsertModifiedQuery = `
+INSERT INTO user_calendar(user_id, etag, modified_at) VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET etag = EXCLUDED.etag, modified_at = EXCLUDED.modified_at
+`
+
+// updateModifiedAt returns the modification time of the feed of the user,
+// which is only written if the digest has changed since the last request,
+// so most polls only read it.
+func updateModifiedAt(conn *db.Conn, userId int, digest string) (time.Time, error) {
+	var stored *storedDigest
+	var row storedDigest
+	err := conn.QueryRow(selectModifiedQuery, userId).Scan(&row.Digest, &row.ModifiedAt)
+	if err == nil {
+		stored = &row
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("reading modification time: %w", err)
+	}
+
+	modifiedAt, changed := nextModifiedAt(stored, digest, time.Now())
+	if changed {
+		_, err = conn.Exec(upsertModifiedQuery, userId, digest, modifiedAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("updating modification time: %w", err)
+		}
+	}
+	return modifiedAt, nil
+}
+
+func postgresToWebcalEvent(event *postgresEvent, date time.Time, options *calendarOptions) *webcalEvent {
 	var location string
 	if event.Location != nil {
 		location = *event.Location
@@ -205,43 +480,166 @@ func postgresToWebcalEvent(event *postgresEvent, date time.Time) *webcalEvent {
 	}
 
 	var summary = strings.ToUpper(event.CourseCode)
-	summary = fmt.Sprintf("%s - %s", summary, event.SectionName)
+	if options.Summary == summaryName {
+		summary = fmt.Sprintf("%s - %s", summary, event.CourseName)
+	} else {
+		summary = fmt.Sprintf("%s - %s", summary, event.SectionName)
+	}
 
+	startTime := date.Add(time.Second * time.Duration(event.StartSeconds))
 	return &webcalEvent{
-		GroupId:   event.SectionId,
-		Summary:   summary,
-		StartTime: date.Add(time.Second * time.Duration(event.StartSeconds)).UTC(),
-		EndTime:   date.Add(time.Second * time.Duration(event.EndSeconds)).UTC(),
-		Location:  location,
+		GroupId:      event.SectionId,
+		Tag:          strconv.FormatInt(startTime.Unix(), 10),
+		Summary:      summary,
+		StartTime:    startTime,
+		EndTime:      date.Add(time.Second * time.Duration(event.EndSeconds)),
+		Location:     location,
+		AlarmMinutes: options.AlarmMinutes,
 	}
 }
 
-func postgresToWebcalEvents(events []*postgresEvent) ([]*webcalEvent, error) {
+// postgresToWebcalExam converts an exam into an event.
+// Its summary is always of the form "ECE105 - FINAL", as that is what sets it apart.
+func postgresToWebcalExam(exam *postgresExam, options *calendarOptions) *webcalEvent {
+	var location string
+	if exam.Location != nil {
+		location = *exam.Location
+	} else {
+		location = "Unknown"
+	}
+
+	return &webcalEvent{
+		GroupId:      exam.SectionId,
+		Tag:          "FINAL",
+		Summary:      fmt.Sprintf("%s - FINAL", strings.ToUpper(exam.CourseCode)),
+		StartTime:    exam.Date.Add(time.Second * time.Duration(exam.StartSeconds)),
+		EndTime:      exam.Date.Add(time.Second * time.Duration(exam.EndSeconds)),
+		Location:     location,
+		AlarmMinutes: options.AlarmMinutes,
+	}
+}
+
+// postgresToWebcalSeries converts a meeting into a weekly recurring event,
+// or nil if the meeting never takes place.
+// Occurrences on holidays are excluded, and those at either end are dropped entirely.
+func postgresToWebcalSeries(
+	event *postgresEvent, holidays map[string]bool, options *calendarOptions,
+) *webcalEvent {
+	var series *webcalEvent
+	// Excluded occurrences since the last occurrence that does take place
+	var pending []time.Time
+
+	var days []string
+	for i, hasDay := range event.HasDay {
+		if hasDay {
+			days = append(days, indexToByDay[i])
+		}
+	}
+
+	// Walk entire date range for each event. It would be more efficient
+	// to map days to events and walk the union of date ranges for all events simultaneously,
+	// but this is so fast as-is that the difference is negligible.
+	for date := event.StartDate; !date.After(event.EndDate); date = date.AddDate(0, 0, 1) {
+		if !event.HasDay[int(date.Weekday())] {
+			continue
+		}
+
+		startTime := date.Add(time.Second * time.Duration(event.StartSeconds))
+		if holidays[date.Format(dbDateFormat)] {
+			if series != nil {
+				pending = append(pending, startTime)
+			}
+			continue
+		}
+
+		if series == nil {
+			series = postgresToWebcalEvent(event, date, options)
+			series.Days = days
+		} else {
+			series.ExDates = append(series.ExDates, pending...)
+		}
+		pending = nil
+		series.Until = startTime
+	}
+
+	return series
+}
+
+// postgresToWebcalEvents converts the meetings and exams selected by options into events.
+// Component filters apply only to meetings: exams are not associated with a component.
+func postgresToWebcalEvents(
+	events []*postgresEvent, exams []*postgresExam, holidays map[string]bool, options *calendarOptions,
+) ([]*webcalEvent, error) {
 	var webcalEvents []*webcalEvent
 
 	for _, event := range events {
-		// Walk entire date range for each event. It would be more efficient
-		// to map days to events and walk the union of date ranges for all events simultaneously,
-		// but this is so fast as-is that the difference is negligible.
-		for date := event.StartDate; !date.After(event.EndDate); date = date.AddDate(0, 0, 1) {
-			if event.HasDay[int(date.Weekday())] {
-				webcalEvents = append(webcalEvents, postgresToWebcalEvent(event, date))
-			}
+		if !options.includes(event.SectionName, event.TermId) {
+			continue
+		}
+		if series := postgresToWebcalSeries(event, holidays, options); series != nil {
+			webcalEvents = append(webcalEvents, series)
 		}
 	}
 
+	for _, exam := range exams {
+		if options.TermId != 0 && exam.TermId != options.TermId {
+			continue
+		}
+		webcalEvents = append(webcalEvents, postgresToWebcalExam(exam, options))
+	}
+
 	return webcalEvents, nil
 }
 
 func HandleCalendar(conn *db.Conn, w http.ResponseWriter, r *http.Request) error {
 	secretId := chi.URLParam(r, "secretId")
 
-	events, err := extractUserEvents(conn, secretId)
+	options, err := parseOptions(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	userId, err := extractUserId(conn, secretId)
+	if err != nil {
+		return fmt.Errorf("extracting user id: %w", err)
+	}
+
+	digest, err := extractDigest(conn, userId)
+	if err != nil {
+		return fmt.Errorf("extracting digest: %w", err)
+	}
+
+	modifiedAt, err := updateModifiedAt(conn, userId, digest)
+	if err != nil {
+		return err
+	}
+
+	etag := makeEtag(digest, options)
+	// Calendar clients poll feeds periodically, and most polls find nothing new.
+	// The URL is a secret, so the feed must not be stored by shared caches.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	writeValidators(w, etag, modifiedAt)
+	if notModified(r, etag, modifiedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	events, err := extractUserEvents(conn, userId)
+	if err != nil {
+		return fmt.Errorf("extracting events: %w", err)
+	}
+
+	exams, err := extractUserExams(conn, userId)
+	if err != nil {
+		return fmt.Errorf("extracting exams: %w", err)
+	}
+
+	holidays, err := extractHolidays(conn)
 	if err != nil {
-		return serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting events: %w", err))
+		return fmt.Errorf("extracting holidays: %w", err)
 	}
 
-	webcalEvents, err := postgresToWebcalEvents(events)
+	webcalEvents, err := postgresToWebcalEvents(events, exams, holidays, options)
 	if err != nil {
 		return fmt.Errorf("converting events: %w", err)
 	}
@@ -250,8 +648,8 @@ func HandleCalendar(conn *db.Conn, w http.ResponseWriter, r *http.Request) error
 	w.Header().Set("Content-Disposition", "attachment; filename=uwflow.ics")
 	// Google Calendar requires explicit charset
 	w.Header().Set("Content-Type", `text/calendar; charset="utf-8"`)
-	w.WriteHeader(http.StatusCreated)
-	writeCalendar(w, secretId, webcalEvents)
+	w.WriteHeader(http.StatusOK)
+	writeCalendar(w, secretId, webcalEvents, modifiedAt)
 
 	return nil
 }
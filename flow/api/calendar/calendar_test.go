@@ -2,11 +2,18 @@ package calendar
 
 import (
 	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"flow/common/util"
 )
 
+var update = flag.Bool("update", false, "update golden files in testdata")
+
 func TestWriteCalendar(t *testing.T) {
 	startTime := time.Date(2023, 10, 25, 14, 30, 0, 0, time.UTC)
 	events := []*webcalEvent{
@@ -20,11 +27,319 @@ func TestWriteCalendar(t *testing.T) {
 	}
 
 	var output bytes.Buffer
-	writeCalendar(&output, "test_secret_id", events)
+	writeCalendar(&output, "test_secret_id", events, time.Now())
 	result := output.String()
 
-	expectedTimestamp := "20231025T143000Z"
-	if !strings.Contains(result, "DTSTART:"+expectedTimestamp) {
+	expectedTimestamp := "20231025T103000"
+	if !strings.Contains(result, "DTSTART;TZID=America/Toronto:"+expectedTimestamp) {
 		t.Errorf("Expected output to contain start time %q, but got:\n%s", expectedTimestamp, result)
 	}
 }
+
+func TestWriteCalendarExam(t *testing.T) {
+	location := "PAC 1, 2, 3"
+	exam := &postgresExam{
+		SectionId:    4896,
+		CourseCode:   "ece105",
+		Location:     &location,
+		Date:         time.Date(2019, 12, 10, 0, 0, 0, 0, util.UniversityLocation),
+		StartSeconds: 9 * 3600,
+		EndSeconds:   11*3600 + 30*60,
+	}
+
+	events, err := postgresToWebcalEvents(nil, []*postgresExam{exam}, nil, &calendarOptions{})
+	if err != nil {
+		t.Fatalf("converting: %v", err)
+	}
+
+	var output bytes.Buffer
+	writeCalendar(&output, "test_secret_id", events, time.Now())
+	result := output.String()
+
+	for _, want := range []string{
+		"SUMMARY:ECE105 - FINAL\r\n",
+		"UID:-//uwflow.com//test_secret_id//4896//FINAL//EN\r\n",
+		"DTSTART;TZID=America/Toronto:20191210T090000\r\n",
+		"DTEND;TZID=America/Toronto:20191210T113000\r\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, but got:\n%s", want, result)
+		}
+	}
+}
+
+func TestPostgresToWebcalSeries(t *testing.T) {
+	location := "MC 4040"
+	event := &postgresEvent{
+		SectionId:    6513,
+		CourseCode:   "co255",
+		SectionName:  "LEC 001",
+		Location:     &location,
+		StartDate:    time.Date(2020, 1, 6, 0, 0, 0, 0, util.UniversityLocation),
+		EndDate:      time.Date(2020, 1, 31, 0, 0, 0, 0, util.UniversityLocation),
+		StartSeconds: 14*3600 + 30*60,
+		EndSeconds:   15*3600 + 50*60,
+		HasDay:       [7]bool{1: true, 3: true},
+	}
+	holidays := map[string]bool{"2020-01-15": true}
+
+	events, err := postgresToWebcalEvents([]*postgresEvent{event}, nil, holidays, &calendarOptions{})
+	if err != nil {
+		t.Fatalf("converting: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected a single recurring event, but got %d", len(events))
+	}
+
+	var output bytes.Buffer
+	writeCalendar(&output, "test_secret_id", events, time.Now())
+	result := output.String()
+
+	for _, want := range []string{
+		"UID:-//uwflow.com//test_secret_id//6513//1578339000//EN\r\n",
+		"DTSTART;TZID=America/Toronto:20200106T143000\r\n",
+		"DTEND;TZID=America/Toronto:20200106T155000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20200129T193000Z\r\n",
+		"EXDATE;TZID=America/Toronto:20200115T143000\r\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, but got:\n%s", want, result)
+		}
+	}
+}
+
+func TestPostgresToWebcalSeriesDst(t *testing.T) {
+	event := &postgresEvent{
+		SectionId:    4896,
+		CourseCode:   "cs145",
+		SectionName:  "LEC 001",
+		StartDate:    time.Date(2020, 10, 20, 0, 0, 0, 0, util.UniversityLocation),
+		EndDate:      time.Date(2020, 11, 12, 0, 0, 0, 0, util.UniversityLocation),
+		StartSeconds: 10 * 3600,
+		EndSeconds:   11*3600 + 20*60,
+		HasDay:       [7]bool{2: true, 4: true},
+	}
+
+	series := postgresToWebcalSeries(event, nil, &calendarOptions{})
+	if series == nil {
+		t.Fatalf("Expected a recurring event, but got none")
+	}
+
+	// Local times are unaffected by DST, so the series need not be split.
+	wantStart := time.Date(2020, 10, 20, 10, 0, 0, 0, util.UniversityLocation)
+	wantUntil := time.Date(2020, 11, 12, 10, 0, 0, 0, util.UniversityLocation)
+	if !series.StartTime.Equal(wantStart) {
+		t.Errorf("Expected series to start at %v, but got %v", wantStart, series.StartTime)
+	}
+	if !series.Until.Equal(wantUntil) {
+		t.Errorf("Expected series to end at %v, but got %v", wantUntil, series.Until)
+	}
+}
+
+// checkRfc5545 checks the structural requirements of RFC 5545 that our output must meet.
+func checkRfc5545(t *testing.T, result string) {
+	t.Helper()
+
+	// 3.1 Content lines are delimited by CRLF and SHOULD NOT be longer than 75 octets.
+	if !strings.HasSuffix(result, "\r\n") {
+		t.Errorf("Expected output to end with CRLF")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(result, "\r\n"), "\r\n") {
+		if strings.Contains(line, "\n") {
+			t.Errorf("Expected line %q to be delimited by CRLF", line)
+		}
+		if len(line) > 75 {
+			t.Errorf("Expected line %q to be at most 75 octets", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(result, "\r\n"), "\r\n ", "")
+	var stack []string
+	timezones := make(map[string]bool)
+	for _, line := range strings.Split(unfolded, "\r\n") {
+		name, value, _ := strings.Cut(line, ":")
+		switch {
+		case name == "BEGIN":
+			stack = append(stack, value)
+		case name == "END":
+			if len(stack) == 0 || stack[len(stack)-1] != value {
+				t.Errorf("Unexpected END:%s in %v", value, stack)
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case name == "TZID":
+			timezones[value] = true
+		}
+
+		// 3.6.5 Every TZID parameter must refer to a preceding VTIMEZONE.
+		if _, tzid, ok := strings.Cut(name, ";TZID="); ok && !timezones[tzid] {
+			t.Errorf("Expected VTIMEZONE for %q to precede line %q", tzid, line)
+		}
+	}
+	if len(stack) != 0 {
+		t.Errorf("Expected all components to end, but %v did not", stack)
+	}
+}
+
+func TestWriteCalendarGolden(t *testing.T) {
+	location := "MC 4040"
+	multiRoom := "MC 4020, DC 1350; see https://uwaterloo.ca/registrar/final-examinations"
+	tests := []struct {
+		name    string
+		events  []*postgresEvent
+		exams   []*postgresExam
+		options calendarOptions
+	}{
+		{
+			name: "winter",
+			events: []*postgresEvent{
+				{
+					SectionId:    6513,
+					CourseCode:   "co255",
+					SectionName:  "LEC 001",
+					Location:     &location,
+					StartDate:    time.Date(2020, 1, 6, 0, 0, 0, 0, util.UniversityLocation),
+					EndDate:      time.Date(2020, 4, 3, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 14*3600 + 30*60,
+					EndSeconds:   15*3600 + 50*60,
+					HasDay:       [7]bool{1: true, 3: true},
+				},
+			},
+			exams: []*postgresExam{
+				{
+					SectionId:    6513,
+					CourseCode:   "co255",
+					Location:     &location,
+					Date:         time.Date(2020, 4, 14, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 9 * 3600,
+					EndSeconds:   11*3600 + 30*60,
+				},
+			},
+		},
+		{
+			name: "fall",
+			events: []*postgresEvent{
+				{
+					SectionId:    4896,
+					CourseCode:   "cs145",
+					SectionName:  "LEC 001",
+					StartDate:    time.Date(2020, 9, 8, 0, 0, 0, 0, util.UniversityLocation),
+					EndDate:      time.Date(2020, 12, 8, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 10 * 3600,
+					EndSeconds:   11*3600 + 20*60,
+					HasDay:       [7]bool{2: true, 4: true},
+				},
+			},
+		},
+		{
+			name: "escaping",
+			exams: []*postgresExam{
+				{
+					SectionId:    2219,
+					CourseCode:   "math135",
+					Location:     &multiRoom,
+					Date:         time.Date(2020, 12, 14, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 19 * 3600,
+					EndSeconds:   21*3600 + 30*60,
+				},
+			},
+		},
+		{
+			name: "options",
+			events: []*postgresEvent{
+				{
+					SectionId:    6513,
+					CourseCode:   "co255",
+					CourseName:   "Introduction to Optimization (Advanced Level)",
+					SectionName:  "LEC 001",
+					TermId:       1201,
+					Location:     &location,
+					StartDate:    time.Date(2020, 1, 6, 0, 0, 0, 0, util.UniversityLocation),
+					EndDate:      time.Date(2020, 1, 31, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 14*3600 + 30*60,
+					EndSeconds:   15*3600 + 50*60,
+					HasDay:       [7]bool{1: true, 3: true},
+				},
+				{
+					SectionId:    6514,
+					CourseCode:   "co255",
+					CourseName:   "Introduction to Optimization (Advanced Level)",
+					SectionName:  "TUT 101",
+					TermId:       1201,
+					StartDate:    time.Date(2020, 1, 6, 0, 0, 0, 0, util.UniversityLocation),
+					EndDate:      time.Date(2020, 1, 31, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 16 * 3600,
+					EndSeconds:   16*3600 + 50*60,
+					HasDay:       [7]bool{5: true},
+				},
+				{
+					SectionId:    4896,
+					CourseCode:   "cs145",
+					CourseName:   "Designing Functional Programs (Advanced Level)",
+					SectionName:  "LEC 001",
+					TermId:       1209,
+					StartDate:    time.Date(2020, 9, 8, 0, 0, 0, 0, util.UniversityLocation),
+					EndDate:      time.Date(2020, 12, 8, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 10 * 3600,
+					EndSeconds:   11*3600 + 20*60,
+					HasDay:       [7]bool{2: true, 4: true},
+				},
+			},
+			exams: []*postgresExam{
+				{
+					SectionId:    6513,
+					CourseCode:   "co255",
+					TermId:       1201,
+					Date:         time.Date(2020, 4, 14, 0, 0, 0, 0, util.UniversityLocation),
+					StartSeconds: 9 * 3600,
+					EndSeconds:   11*3600 + 30*60,
+				},
+			},
+			options: calendarOptions{
+				Components:   map[string]bool{"LEC": true},
+				Summary:      summaryName,
+				AlarmMinutes: 15,
+				TermId:       1201,
+			},
+		},
+		{
+			name: "empty",
+		},
+	}
+	createTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	holidays := map[string]bool{
+		"2020-02-17": true,
+		"2020-02-19": true,
+		"2020-04-10": true,
+		"2020-10-13": true,
+		"2020-10-15": true,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := postgresToWebcalEvents(tt.events, tt.exams, holidays, &tt.options)
+			if err != nil {
+				t.Fatalf("converting: %v", err)
+			}
+
+			var output bytes.Buffer
+			writeCalendar(&output, "test_secret_id", events, createTime)
+			result := output.String()
+			checkRfc5545(t, result)
+
+			path := filepath.Join("testdata", tt.name+".ics")
+			if *update {
+				if err := os.WriteFile(path, []byte(result), 0644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file: %v", err)
+			}
+			if result != string(want) {
+				t.Errorf("Output differs from %s:\n%s", path, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagMatches reports whether the value of an If-None-Match header matches etag.
+//
+// RFC 9110 13.1.2: A recipient MUST use the weak comparison function
+// when comparing entity tags for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the client already has the current representation,
+// which has the given etag and modification time.
+//
+// RFC 9110 13.1.3: A recipient MUST ignore If-Modified-Since
+// if the request contains an If-None-Match header field.
+func notModified(r *http.Request, etag string, modifiedAt time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etagMatches(header, etag)
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		// HTTP dates have a resolution of one second
+		return err == nil && !modifiedAt.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// writeValidators sets the response headers that allow clients to make conditional requests.
+func writeValidators(w http.ResponseWriter, etag string, modifiedAt time.Time) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modifiedAt.UTC().Format(http.TimeFormat))
+}
+
+// storedDigest is the digest of the data of a feed as of the last request for it.
+type storedDigest struct {
+	Digest     string
+	ModifiedAt time.Time
+}
+
+// nextModifiedAt returns the modification time of a feed whose data has the given digest,
+// and whether it changed from stored, which is nil if the feed was never requested.
+// Options are not part of the digest, so polls with different options do not move it.
+func nextModifiedAt(stored *storedDigest, digest string, now time.Time) (time.Time, bool) {
+	if stored != nil && stored.Digest == digest {
+		return stored.ModifiedAt, false
+	}
+	return now, true
+}
@@ -0,0 +1,87 @@
+package calendar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	etag := `"1-d41d8cd98f00b204e9800998ecf8427e"`
+	modifiedAt := time.Date(2020, 1, 6, 14, 30, 15, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"unconditional", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": etag}, true},
+		{"weak etag", map[string]string{"If-None-Match": "W/" + etag}, true},
+		{"etag in list", map[string]string{"If-None-Match": `"0-abc", ` + etag}, true},
+		{"wildcard", map[string]string{"If-None-Match": "*"}, true},
+		{"stale etag", map[string]string{"If-None-Match": `"0-abc"`}, false},
+		{"same time", map[string]string{"If-Modified-Since": "Mon, 06 Jan 2020 14:30:15 GMT"}, true},
+		{"later time", map[string]string{"If-Modified-Since": "Tue, 07 Jan 2020 00:00:00 GMT"}, true},
+		{"earlier time", map[string]string{"If-Modified-Since": "Mon, 06 Jan 2020 14:30:14 GMT"}, false},
+		{"invalid time", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{
+			"etag takes precedence",
+			map[string]string{
+				"If-None-Match":     `"0-abc"`,
+				"If-Modified-Since": "Tue, 07 Jan 2020 00:00:00 GMT",
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/calendar/ABCDEFGHIJKLMNOP.ics", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			if got := notModified(r, etag, modifiedAt); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModifiedAtAcrossOptions(t *testing.T) {
+	digest := "1-d41d8cd98f00b204e9800998ecf8427e"
+	first := time.Date(2020, 1, 6, 14, 30, 15, 0, time.UTC)
+
+	var stored *storedDigest
+	var etags []string
+	queries := []url.Values{{}, {"alarm": {"15"}}, {"term": {"1201"}}, {}}
+	for i, query := range queries {
+		options, err := parseOptions(query)
+		if err != nil {
+			t.Fatalf("parsing %v: %v", query, err)
+		}
+		now := first.Add(time.Duration(i) * time.Hour)
+		modifiedAt, changed := nextModifiedAt(stored, digest, now)
+		if changed != (i == 0) {
+			t.Errorf("poll %d: changed = %v", i, changed)
+		}
+		if !modifiedAt.Equal(first) {
+			t.Errorf("poll %d: modified at %v, want %v", i, modifiedAt, first)
+		}
+		if changed {
+			stored = &storedDigest{Digest: digest, ModifiedAt: modifiedAt}
+		}
+		etags = append(etags, makeEtag(digest, options))
+	}
+	if etags[0] == etags[1] || etags[0] != etags[3] {
+		t.Errorf("expected etags to differ by options only, got %v", etags)
+	}
+
+	// Once the schedule changes, so does the modification time
+	later := first.Add(24 * time.Hour)
+	if modifiedAt, changed := nextModifiedAt(stored, "1-other", later); !changed || !modifiedAt.Equal(later) {
+		t.Errorf("expected a new digest to move the modification time, got %v (changed: %v)", modifiedAt, changed)
+	}
+}
@@ -0,0 +1,102 @@
+package calendar
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// 3.1 CONTENT LINES
+//   - delimited by a line break, which is a CRLF sequence
+//   - Lines of text SHOULD NOT be longer than 75 octets, excluding the line break
+//   - Long content lines SHOULD be split into a multiple line representations
+//     using a line "folding" technique
+const maxLineOctets = 75
+
+// 3.3.11 TEXT
+//   - a BACKSLASH character MUST be escaped with another BACKSLASH character
+//   - a COMMA character [and] a SEMICOLON character MUST be escaped with a BACKSLASH character
+//   - An intentional formatted text line break MUST only be included [...]
+//     by a BACKSLASH character followed by a LATIN SMALL LETTER N
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// fold splits a content line into lines of at most maxLineOctets octets.
+//
+// 3.1 CONTENT LINES
+//   - a long line can be split between any two characters by inserting a CRLF
+//     immediately followed by a single linear white-space character
+//   - Care MUST be taken [...] to not fold the line in the middle of a UTF-8 multi-octet sequence
+//
+// The inserted white-space counts towards the length of the continuation line.
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+
+	var folded strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+	folded.WriteString(line)
+	return folded.String()
+}
+
+// 3.2 PROPERTY PARAMETERS
+//   - Property parameter values that contain the COLON, SEMICOLON, or COMMA character separators
+//     MUST be specified as quoted-string text values
+func withParam(name, param, value string) string {
+	if strings.ContainsAny(value, ":;,") {
+		value = `"` + value + `"`
+	}
+	return name + ";" + param + "=" + value
+}
+
+// encoder writes an iCalendar object as a sequence of content lines.
+// Like fmt.Fprintf, it does not report write errors:
+// these can only come from the client going away, which we cannot do anything about.
+type encoder struct {
+	w io.Writer
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{w: w}
+}
+
+// property writes a content line with the value as-is.
+// This is appropriate for value types whose grammar excludes characters that need escaping,
+// such as DATE-TIME, RECUR and UTC-OFFSET. The name may include parameters (see withParam).
+func (e *encoder) property(name, value string) {
+	io.WriteString(e.w, fold(name+":"+value)+"\r\n")
+}
+
+// text writes a content line with a TEXT value, escaping it as required.
+func (e *encoder) text(name, value string) {
+	e.property(name, escapeText(value))
+}
+
+func (e *encoder) begin(component string) {
+	e.property("BEGIN", component)
+}
+
+func (e *encoder) end(component string) {
+	e.property("END", component)
+}
@@ -0,0 +1,80 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"MC 4085", "MC 4085"},
+		{"MC 4020, DC 1350", `MC 4020\, DC 1350`},
+		{`a;b\c`, `a\;b\\c`},
+		{"first\r\nsecond\nthird", `first\nsecond\nthird`},
+	}
+
+	for _, tt := range tests {
+		got := escapeText(tt.input)
+		if got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"short", "LOCATION:MC 4085"},
+		{"exact", "X:" + strings.Repeat("a", maxLineOctets-2)},
+		{"long", "DESCRIPTION:" + strings.Repeat("0123456789", 20)},
+		// é is two octets, so a naive split would land in the middle of one
+		{"multibyte", "SUMMARY:" + strings.Repeat("é", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := fold(tt.input)
+			for i, line := range strings.Split(folded, "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("Expected line %q to be at most %d octets", line, maxLineOctets)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("Expected continuation line %q to start with a space", line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("Expected line %q to be valid UTF-8", line)
+				}
+			}
+			if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != tt.input {
+				t.Errorf("Expected unfolding to give %q, but got %q", tt.input, unfolded)
+			}
+		})
+	}
+}
+
+func TestWithParam(t *testing.T) {
+	if got := withParam("DTSTART", "TZID", "America/Toronto"); got != "DTSTART;TZID=America/Toronto" {
+		t.Errorf("Unexpected unquoted parameter: %q", got)
+	}
+	if got := withParam("X-NAME", "X-PARAM", "a:b"); got != `X-NAME;X-PARAM="a:b"` {
+		t.Errorf("Unexpected quoted parameter: %q", got)
+	}
+}
+
+func TestEncoderText(t *testing.T) {
+	var output bytes.Buffer
+	enc := newEncoder(&output)
+	enc.text("LOCATION", "MC 4020, DC 1350")
+
+	want := "LOCATION:MC 4020\\, DC 1350\r\n"
+	if output.String() != want {
+		t.Errorf("Expected %q, but got %q", want, output.String())
+	}
+}
@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"flow/api/serde"
+	"flow/common/util"
+)
+
+type summaryFormat int
+
+const (
+	// Summaries look like "CS135 - LEC 001"
+	summarySection summaryFormat = iota
+	// Summaries look like "CS135 - Designing Functional Programs"
+	summaryName
+)
+
+var summaryFormats = map[string]summaryFormat{
+	"section": summarySection,
+	"name":    summaryName,
+}
+
+// Section components as they appear at the start of section names, e.g. "LEC 001".
+var validComponents = map[string]bool{
+	"CLN": true, "DIS": true, "ENS": true, "ESS": true, "FLD": true, "LAB": true,
+	"LEC": true, "OPN": true, "PRA": true, "PRJ": true, "RDG": true, "SEM": true,
+	"STU": true, "TLC": true, "TST": true, "TUT": true, "WRK": true, "WSP": true,
+}
+
+// Reminders further in advance than a week are not useful for weekly events.
+const maxAlarmMinutes = 7 * 24 * 60
+
+// calendarOptions customize the contents of a webcal feed.
+// They are given as query parameters of the feed URL, so that each subscription can differ.
+// The zero value corresponds to a feed without any parameters.
+type calendarOptions struct {
+	// Components of sections to include, e.g. "LEC". If empty, all components are included.
+	Components map[string]bool
+	// What follows the course code in event summaries
+	Summary summaryFormat
+	// If positive, a reminder is given this many minutes before each event
+	AlarmMinutes int
+	// If nonzero, only events from this term are included
+	TermId int
+}
+
+func invalidOption(format string, args ...interface{}) error {
+	return serde.WithStatus(
+		http.StatusBadRequest,
+		serde.WithEnum(serde.InvalidCalendarOption, fmt.Errorf(format, args...)),
+	)
+}
+
+// parseOptions parses options from query parameters, e.g.
+// ?components=LEC,TUT&summary=name&alarm=15&term=1209
+func parseOptions(query url.Values) (*calendarOptions, error) {
+	var options calendarOptions
+
+	if value := query.Get("components"); value != "" {
+		options.Components = make(map[string]bool)
+		for _, component := range strings.Split(value, ",") {
+			component = strings.ToUpper(strings.TrimSpace(component))
+			if !validComponents[component] {
+				return nil, invalidOption("unknown component: %q", component)
+			}
+			options.Components[component] = true
+		}
+	}
+
+	if value := query.Get("summary"); value != "" {
+		format, ok := summaryFormats[value]
+		if !ok {
+			return nil, invalidOption("unknown summary format: %q", value)
+		}
+		options.Summary = format
+	}
+
+	if value := query.Get("alarm"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 || minutes > maxAlarmMinutes {
+			return nil, invalidOption("alarm must be between 0 and %d minutes: %q", maxAlarmMinutes, value)
+		}
+		options.AlarmMinutes = minutes
+	}
+
+	if value := query.Get("term"); value != "" {
+		termId, err := strconv.Atoi(value)
+		if err != nil || !util.IsValidTermId(termId) {
+			return nil, invalidOption("invalid term id: %q", value)
+		}
+		options.TermId = termId
+	}
+
+	return &options, nil
+}
+
+// includes reports whether a meeting of the given section in the given term belongs in the feed.
+func (o *calendarOptions) includes(sectionName string, termId int) bool {
+	if o.TermId != 0 && termId != o.TermId {
+		return false
+	}
+	if len(o.Components) > 0 {
+		component, _, _ := strings.Cut(sectionName, " ")
+		return o.Components[component]
+	}
+	return true
+}
+
+// String returns a canonical representation of the options,
+// which is the same for all query strings that parse to the same options.
+func (o *calendarOptions) String() string {
+	components := make([]string, 0, len(o.Components))
+	for component := range o.Components {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return fmt.Sprintf(
+		"components=%s&summary=%d&alarm=%d&term=%d",
+		strings.Join(components, ","), o.Summary, o.AlarmMinutes, o.TermId,
+	)
+}
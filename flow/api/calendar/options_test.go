@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"flow/api/serde"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		want  calendarOptions
+	}{
+		{"", calendarOptions{}},
+		{
+			"components=lec,TUT&summary=name&alarm=15&term=1209",
+			calendarOptions{
+				Components:   map[string]bool{"LEC": true, "TUT": true},
+				Summary:      summaryName,
+				AlarmMinutes: 15,
+				TermId:       1209,
+			},
+		},
+		{"summary=section&alarm=0", calendarOptions{}},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := parseOptions(query)
+		if err != nil {
+			t.Errorf("parseOptions(%q): unexpected error: %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("parseOptions(%q) = %+v, want %+v", tt.query, *got, tt.want)
+		}
+	}
+}
+
+func TestParseOptionsInvalid(t *testing.T) {
+	for _, input := range []string{
+		"components=LEC,XYZ",
+		"summary=prof",
+		"alarm=-5",
+		"alarm=soon",
+		"alarm=100000",
+		"term=1208",
+		"term=2020",
+	} {
+		query, _ := url.ParseQuery(input)
+		_, err := parseOptions(query)
+		var enumErr interface{ Enum() string }
+		if !errors.As(err, &enumErr) || enumErr.Enum() != serde.InvalidCalendarOption {
+			t.Errorf("parseOptions(%q): expected %s, but got %v", input, serde.InvalidCalendarOption, err)
+		}
+	}
+}
+
+func TestOptionsString(t *testing.T) {
+	a, _ := parseOptions(url.Values{"components": {"TUT,LEC"}, "alarm": {"10"}})
+	b, _ := parseOptions(url.Values{"components": {"lec, tut"}, "alarm": {"10"}, "summary": {"section"}})
+	if a.String() != b.String() {
+		t.Errorf("Expected equivalent options to have the same representation: %q != %q", a, b)
+	}
+}
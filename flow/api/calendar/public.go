@@ -0,0 +1,176 @@
+package calendar
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flow/api/serde"
+	"flow/common/db"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Public feeds are the same for everyone, so shared caches may store them.
+// Sections are imported hourly, so there is no point in refetching more often than that.
+const publicMaxAge = time.Hour
+
+const selectPublicEventTemplate = `
+SELECT
+  sm.section_id, c.code, c.name, cs.section_name, cs.term_id, sm.location,
+  sm.start_date :: TEXT, sm.end_date :: TEXT,
+  sm.start_seconds, sm.end_seconds, sm.days
+FROM
+  course_section cs
+  JOIN section_meeting sm ON sm.section_id = cs.id
+  JOIN course c ON c.id = cs.course_id
+WHERE %s
+  AND sm.start_seconds IS NOT NULL
+  AND sm.end_seconds IS NOT NULL
+`
+
+const selectPublicExamTemplate = `
+SELECT
+  se.section_id, c.code, c.name, cs.term_id, se.location,
+  se.date :: TEXT, se.start_seconds, se.end_seconds
+FROM
+  course_section cs
+  JOIN section_exam se ON se.section_id = cs.id
+  JOIN course c ON c.id = cs.course_id
+WHERE %s
+  AND NOT se.is_tba
+  AND se.date IS NOT NULL
+  AND se.start_seconds IS NOT NULL
+  AND se.end_seconds IS NOT NULL
+`
+
+// This is NULL if and only if there are no matching sections.
+const selectPublicUpdatedTemplate = `
+SELECT MAX(cs.updated_at)
+FROM
+  course_section cs
+  JOIN course c ON c.id = cs.course_id
+WHERE %s
+`
+
+const (
+	sectionCondition = `cs.term_id = $1 AND cs.class_number = $2`
+	courseCondition  = `cs.term_id = $1 AND c.code = $2`
+)
+
+// publicFeed describes a feed of all sections matching a condition on course_section cs and course c.
+type publicFeed struct {
+	// Identifies the feed in PRODID and UIDs, e.g. section-1209-4896
+	Id        string
+	Condition string
+	Args      []interface{}
+}
+
+func servePublicFeed(conn *db.Conn, w http.ResponseWriter, r *http.Request, feed *publicFeed) error {
+	options, err := parseOptions(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	var updatedAt *time.Time
+	err = conn.QueryRow(fmt.Sprintf(selectPublicUpdatedTemplate, feed.Condition), feed.Args...).Scan(&updatedAt)
+	if err != nil {
+		return fmt.Errorf("checking sections: %w", err)
+	}
+	if updatedAt == nil {
+		return serde.WithStatus(http.StatusNotFound, fmt.Errorf("no sections for %s", feed.Id))
+	}
+
+	events, err := extractEvents(conn, fmt.Sprintf(selectPublicEventTemplate, feed.Condition), feed.Args...)
+	if err != nil {
+		return fmt.Errorf("extracting events: %w", err)
+	}
+
+	exams, err := extractExams(conn, fmt.Sprintf(selectPublicExamTemplate, feed.Condition), feed.Args...)
+	if err != nil {
+		return fmt.Errorf("extracting exams: %w", err)
+	}
+
+	holidays, err := extractHolidays(conn)
+	if err != nil {
+		return fmt.Errorf("extracting holidays: %w", err)
+	}
+
+	webcalEvents, err := postgresToWebcalEvents(events, exams, holidays, options)
+	if err != nil {
+		return fmt.Errorf("converting events: %w", err)
+	}
+
+	// Unlike personal feeds, there is nowhere to keep track of modification times,
+	// so the ETag is derived from the feed itself. For this to work,
+	// DTSTAMP must not depend on the time of the request.
+	var body bytes.Buffer
+	writeCalendar(&body, feed.Id, webcalEvents, *updatedAt)
+	etag := fmt.Sprintf(`"%x"`, md5.Sum(body.Bytes()))
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(publicMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.ics", feed.Id))
+	// Google Calendar requires explicit charset
+	w.Header().Set("Content-Type", `text/calendar; charset="utf-8"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+
+	return nil
+}
+
+func termIdFromRequest(r *http.Request) (int, error) {
+	termId, err := strconv.Atoi(chi.URLParam(r, "termId"))
+	if err != nil {
+		return 0, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing term id: %w", err))
+	}
+	return termId, nil
+}
+
+// HandleSectionCalendar serves the meetings and exam of a single section.
+// It requires no authentication, so that anyone can subscribe to it.
+func HandleSectionCalendar(conn *db.Conn, w http.ResponseWriter, r *http.Request) error {
+	termId, err := termIdFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	classNumber, err := strconv.Atoi(chi.URLParam(r, "classNumber"))
+	if err != nil {
+		return serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing class number: %w", err))
+	}
+
+	feed := &publicFeed{
+		Id:        fmt.Sprintf("section-%d-%d", termId, classNumber),
+		Condition: sectionCondition,
+		Args:      []interface{}{termId, classNumber},
+	}
+	return servePublicFeed(conn, w, r, feed)
+}
+
+// HandleCourseCalendar serves the meetings and exams of every section of a course in a term.
+// Subscribers will usually want to narrow it down with the components option.
+func HandleCourseCalendar(conn *db.Conn, w http.ResponseWriter, r *http.Request) error {
+	termId, err := termIdFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	courseCode := strings.ToLower(chi.URLParam(r, "courseCode"))
+
+	feed := &publicFeed{
+		Id:        fmt.Sprintf("course-%d-%s", termId, courseCode),
+		Condition: courseCondition,
+		Args:      []interface{}{termId, courseCode},
+	}
+	return servePublicFeed(conn, w, r, feed)
+}
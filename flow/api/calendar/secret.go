@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"fmt"
+	"net/http"
+
+	"flow/api/auth"
+	"flow/api/serde"
+	"flow/common/db"
+	"flow/common/util/random"
+)
+
+type rotateResponse struct {
+	SecretId string `json:"secret_id"`
+}
+
+const updateSecretIdQuery = `UPDATE "user" SET secret_id = $2 WHERE id = $1`
+
+// RotateSecretId replaces the secret id of the user, which invalidates the URL of their feed.
+// This is the remedy for a leaked feed URL: subscriptions to the old URL stop working.
+func RotateSecretId(tx *db.Tx, r *http.Request) (interface{}, error) {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	secretId, err := random.String(auth.SecretIdLength, random.Uppercase)
+	if err != nil {
+		return nil, fmt.Errorf("generating secret id: %w", err)
+	}
+
+	_, err = tx.Exec(updateSecretIdQuery, userId, secretId)
+	if err != nil {
+		return nil, fmt.Errorf("updating secret id: %w", err)
+	}
+
+	return &rotateResponse{SecretId: secretId}, nil
+}
+
+const updateEnabledQuery = `UPDATE "user" SET calendar_enabled = $2 WHERE id = $1`
+
+func setEnabled(tx *db.Tx, r *http.Request, enabled bool) error {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	_, err = tx.Exec(updateEnabledQuery, userId, enabled)
+	if err != nil {
+		return fmt.Errorf("updating calendar_enabled: %w", err)
+	}
+	return nil
+}
+
+// EnableFeed resumes serving the feed of the user at its current URL.
+func EnableFeed(tx *db.Tx, r *http.Request) error {
+	return setEnabled(tx, r, true)
+}
+
+// DisableFeed stops serving the feed of the user until it is enabled again.
+// Requests for the feed are answered with 404 Not Found in the meantime.
+func DisableFeed(tx *db.Tx, r *http.Request) error {
+	return setEnabled(tx, r, false)
+}
@@ -1,16 +1,81 @@
 package calendar
 
 import (
-	"log"
+	"fmt"
 	"time"
 )
 
-var UniversityLocation *time.Location
+// 3.3.14 UTC OFFSET: ("+" / "-") time-hour time-minute [time-second]
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	hours, minutes, seconds := offset/3600, offset/60%60, offset%60
+	if seconds != 0 {
+		return fmt.Sprintf("%c%02d%02d%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, hours, minutes)
+}
+
+// writeTimezone writes a VTIMEZONE component for loc, with one observance
+// for each zone in effect at some time between from and to.
+//
+// Rather than describing the transitions with recurrence rules,
+// every observance is listed explicitly. This is always exact,
+// as it is derived from the same tz database that we use to compute local times.
+//
+// 3.6.5 Time Zone Component
+// - MUST include the "TZID" property and at least one "STANDARD" or "DAYLIGHT" sub-component
+// - the mapping [...] for a "TZID" value is defined by the "STANDARD" and "DAYLIGHT" sub-components
+func writeTimezone(enc *encoder, loc *time.Location, from, to time.Time) {
+	enc.begin("VTIMEZONE")
+	// 3.8.3.1 TIME ZONE IDENTIFIER: TEXT
+	// - MUST be specified in a "VTIMEZONE" calendar component
+	//
+	// We use the IANA name of the location, e.g. America/Toronto
+	enc.text("TZID", loc.String())
 
-func init() {
-	var err error
-	UniversityLocation, err = time.LoadLocation("Canada/Eastern")
-	if err != nil {
-		log.Fatalf("Error: %s", err)
+	for t := from.In(loc); ; {
+		name, offset := t.Zone()
+		onset, end := t.ZoneBounds()
+
+		// A zone without start has been in effect since the beginning of time.
+		// It suffices to declare that it is in effect from the start of the range.
+		offsetFrom := offset
+		if onset.IsZero() {
+			onset = t
+		} else {
+			_, offsetFrom = onset.Add(-time.Second).Zone()
+		}
+
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		enc.begin(kind)
+		// 3.8.2.4 DTSTART: DATE-TIME
+		// - [defines] the effective onset date and local time for the time zone sub-component
+		// - MUST be specified as a date with a local time value
+		//
+		// The local time is that before the onset, i.e. in the TZOFFSETFROM offset.
+		enc.property("DTSTART", onset.In(time.FixedZone(name, offsetFrom)).Format(icsLocalTimestampFormat))
+		// 3.8.3.3 TIME ZONE OFFSET FROM: UTC-OFFSET
+		// - specifies the offset that is in use prior to this time zone observance
+		enc.property("TZOFFSETFROM", formatOffset(offsetFrom))
+		// 3.8.3.4 TIME ZONE OFFSET TO: UTC-OFFSET
+		// - specifies the offset that is in use in this time zone observance
+		enc.property("TZOFFSETTO", formatOffset(offset))
+		// 3.8.3.2 TIME ZONE NAME: TEXT
+		// - specifies the customary designation for a time zone description
+		enc.text("TZNAME", name)
+		enc.end(kind)
+
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		t = end.In(loc)
 	}
+
+	enc.end("VTIMEZONE")
 }
@@ -50,7 +50,16 @@ FROM prof p
 GROUP BY p.id, pr.filled_count
 `
 
+// HandleSearch searches courses and profs if the q parameter is given.
+// Otherwise, it dumps all of them, as older clients search on their own.
 func HandleSearch(tx *db.Tx, r *http.Request) (interface{}, error) {
+	if r.URL.Query().Has("q") {
+		return handleQuery(tx, r)
+	}
+	return handleDump(tx)
+}
+
+func handleDump(tx *db.Tx) (interface{}, error) {
 	rows, err := tx.Query(courseQuery)
 	if err != nil {
 		return nil, fmt.Errorf("querying courses: %w", err)
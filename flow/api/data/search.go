@@ -0,0 +1,237 @@
+package data
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"flow/api/serde"
+	"flow/common/db"
+	"flow/common/util"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// Deep pages are not useful for search, and each one reruns the whole query
+	maxOffset = 1000
+	// Longer queries are not typed by hand
+	maxTokens = 8
+)
+
+// Delivery modes of course_term_delivery_modes that satisfy each filter
+var deliveryFilters = map[string][]string{
+	"online":    {"ONLINE_ONLY", "BOTH"},
+	"in_person": {"IN_PERSON_ONLY", "BOTH"},
+}
+
+type searchOptions struct {
+	// Prefix query for to_tsquery, e.g. "cs:* & 135:*"
+	TsQuery string
+	// Query without spaces, e.g. cs135, to rank courses by their codes
+	Compact       string
+	IncludeCourse bool
+	IncludeProf   bool
+	// Filters apply only to courses. Zero and nil mean no filter.
+	TermId   int
+	Delivery []string
+	Limit    int
+	Offset   int
+}
+
+// Letters followed by digits are separate tokens, so that "cs135" matches
+// both the code cs135 and the number 135, as indexed by course_search_index.
+// Everything else, such as tsquery operators, is dropped.
+var searchTokenRegexp = regexp.MustCompile(`\p{L}+|\p{N}+\p{L}*`)
+
+func invalidOption(format string, args ...interface{}) error {
+	return serde.WithStatus(
+		http.StatusBadRequest,
+		serde.WithEnum(serde.InvalidSearchOption, fmt.Errorf(format, args...)),
+	)
+}
+
+// parseSearchOptions parses options from query parameters, e.g.
+// ?q=cs 13&type=course&term=1249&delivery=online&limit=20&offset=40
+func parseSearchOptions(query url.Values) (*searchOptions, error) {
+	options := searchOptions{IncludeCourse: true, IncludeProf: true, Limit: defaultLimit}
+
+	tokens := searchTokenRegexp.FindAllString(strings.ToLower(query.Get("q")), -1)
+	if len(tokens) > maxTokens {
+		tokens = tokens[:maxTokens]
+	}
+	options.Compact = strings.Join(tokens, "")
+	for i, token := range tokens {
+		tokens[i] = token + ":*"
+	}
+	options.TsQuery = strings.Join(tokens, " & ")
+
+	switch value := query.Get("type"); value {
+	case "":
+	case "course":
+		options.IncludeProf = false
+	case "prof":
+		options.IncludeCourse = false
+	default:
+		return nil, invalidOption("unknown result type: %q", value)
+	}
+
+	if value := query.Get("term"); value != "" {
+		termId, err := strconv.Atoi(value)
+		if err != nil || !util.IsValidTermId(termId) {
+			return nil, invalidOption("invalid term id: %q", value)
+		}
+		options.TermId = termId
+	}
+
+	if value := query.Get("delivery"); value != "" {
+		modes, ok := deliveryFilters[value]
+		if !ok {
+			return nil, invalidOption("unknown delivery mode: %q", value)
+		}
+		options.Delivery = modes
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return nil, invalidOption("limit must be between 1 and %d: %q", maxLimit, value)
+		}
+		options.Limit = limit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 || offset > maxOffset {
+			return nil, invalidOption("offset must be between 0 and %d: %q", maxOffset, value)
+		}
+		options.Offset = offset
+	}
+
+	return &options, nil
+}
+
+type courseResult struct {
+	Id          int    `json:"id"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	RatingCount int    `json:"rating_count"`
+	// Terms in which the course has sections
+	Terms      []int `json:"terms"`
+	HasPrereqs bool  `json:"has_prereqs"`
+}
+
+type searchResponse struct {
+	Courses []courseResult `json:"courses"`
+	Profs   []prof         `json:"profs"`
+	// Numbers of matches across all pages, or zero if the page is past the last match
+	CourseCount int `json:"course_count"`
+	ProfCount   int `json:"prof_count"`
+}
+
+// Exact code matches come first, then code prefixes, e.g. cs13 for cs135,
+// then the best matches of names. Ties go to the courses with more ratings.
+const searchCoursesQuery = `
+SELECT
+  sc.course_id, sc.code, sc.name, COALESCE(sc.ratings, 0),
+  sc.terms, sc.has_prereqs, COUNT(*) OVER ()
+FROM search_courses($1, FALSE) sc
+WHERE ($2 = 0 OR $2 = ANY(sc.terms))
+  AND ($3::TEXT[] IS NULL OR EXISTS (
+    SELECT FROM course_term_delivery_modes ctdm
+    WHERE ctdm.course_id = sc.course_id
+      AND ctdm.delivery_mode = ANY($3)
+      AND ($2 = 0 OR ctdm.term_id = $2)
+  ))
+ORDER BY
+  sc.code = $4 DESC,
+  sc.code LIKE $4 || '%' DESC,
+  ts_rank(sc.document, to_tsquery('simple', $1)) DESC,
+  sc.ratings DESC NULLS LAST,
+  sc.code
+LIMIT $5 OFFSET $6
+`
+
+// Profs matching by name come before those teaching matching courses
+const searchProfsQuery = `
+SELECT
+  sp.prof_id, sp.code, sp.name, COALESCE(sp.ratings, 0),
+  sp.course_codes, COUNT(*) OVER ()
+FROM search_profs($1, FALSE) sp
+ORDER BY
+  ts_rank(sp.document, to_tsquery('simple', $1)) DESC,
+  sp.ratings DESC NULLS LAST,
+  sp.name
+LIMIT $2 OFFSET $3
+`
+
+func searchCourses(tx *db.Tx, options *searchOptions, response *searchResponse) error {
+	rows, err := tx.Query(
+		searchCoursesQuery,
+		options.TsQuery, options.TermId, options.Delivery, options.Compact, options.Limit, options.Offset,
+	)
+	if err != nil {
+		return fmt.Errorf("searching courses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c courseResult
+		err = rows.Scan(&c.Id, &c.Code, &c.Name, &c.RatingCount, &c.Terms, &c.HasPrereqs, &response.CourseCount)
+		if err != nil {
+			return fmt.Errorf("reading course row: %w", err)
+		}
+		response.Courses = append(response.Courses, c)
+	}
+	return nil
+}
+
+func searchProfs(tx *db.Tx, options *searchOptions, response *searchResponse) error {
+	rows, err := tx.Query(searchProfsQuery, options.TsQuery, options.Limit, options.Offset)
+	if err != nil {
+		return fmt.Errorf("searching profs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p prof
+		err = rows.Scan(&p.Id, &p.Code, &p.Name, &p.RatingCount, &p.Courses, &response.ProfCount)
+		if err != nil {
+			return fmt.Errorf("reading prof row: %w", err)
+		}
+		response.Profs = append(response.Profs, p)
+	}
+	return nil
+}
+
+// handleQuery returns a page of the courses and profs matching the q parameter.
+// Every word of the query is matched as a prefix, so results appear while typing.
+func handleQuery(tx *db.Tx, r *http.Request) (interface{}, error) {
+	options, err := parseSearchOptions(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	response := searchResponse{Courses: []courseResult{}, Profs: []prof{}}
+	// Nothing can match a query without words, and to_tsquery would only complain about it
+	if options.TsQuery == "" {
+		return &response, nil
+	}
+
+	if options.IncludeCourse {
+		err = searchCourses(tx, options, &response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.IncludeProf {
+		err = searchProfs(tx, options, &response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &response, nil
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSearchOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+		want  searchOptions
+	}{
+		{
+			"code",
+			url.Values{"q": {"CS 13"}},
+			searchOptions{TsQuery: "cs:* & 13:*", Compact: "cs13", IncludeCourse: true, IncludeProf: true, Limit: defaultLimit},
+		},
+		{
+			"code_without_space",
+			url.Values{"q": {"ece105"}, "type": {"course"}},
+			searchOptions{TsQuery: "ece:* & 105:*", Compact: "ece105", IncludeCourse: true, Limit: defaultLimit},
+		},
+		{
+			"operators",
+			url.Values{"q": {"!(Zoë | 'x'):*"}, "type": {"prof"}},
+			searchOptions{TsQuery: "zoë:* & x:*", Compact: "zoëx", IncludeProf: true, Limit: defaultLimit},
+		},
+		{
+			"filters",
+			url.Values{"q": {"algorithms"}, "term": {"1249"}, "delivery": {"online"}, "limit": {"5"}, "offset": {"10"}},
+			searchOptions{
+				TsQuery:       "algorithms:*",
+				Compact:       "algorithms",
+				IncludeCourse: true,
+				IncludeProf:   true,
+				TermId:        1249,
+				Delivery:      []string{"ONLINE_ONLY", "BOTH"},
+				Limit:         5,
+				Offset:        10,
+			},
+		},
+		{
+			"empty",
+			url.Values{"q": {" - "}},
+			searchOptions{IncludeCourse: true, IncludeProf: true, Limit: defaultLimit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSearchOptions(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(tt.want, *got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, *got))
+			}
+		})
+	}
+
+	invalid := []url.Values{
+		{"q": {"cs"}, "type": {"course,prof"}},
+		{"q": {"cs"}, "term": {"2024"}},
+		{"q": {"cs"}, "delivery": {"hybrid"}},
+		{"q": {"cs"}, "limit": {"1000"}},
+		{"q": {"cs"}, "offset": {"-1"}},
+	}
+	for _, query := range invalid {
+		if _, err := parseSearchOptions(query); err == nil {
+			t.Errorf("expected an error for %v", query)
+		}
+	}
+}
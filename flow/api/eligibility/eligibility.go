@@ -0,0 +1,156 @@
+package eligibility
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flow/api/serde"
+	"flow/common/db"
+	"flow/common/requirement"
+	"flow/common/util"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type eligibilityResponse struct {
+	CourseCode string `json:"course_code"`
+	Eligible   bool   `json:"eligible"`
+	// Clauses of the requisites that the courses taken do not satisfy,
+	// e.g. "MATH135 or MATH145"
+	UnmetPrereqs []string `json:"unmet_prereqs"`
+	UnmetCoreqs  []string `json:"unmet_coreqs"`
+	// Antirequisites that have been taken
+	TakenAntireqs []string `json:"taken_antireqs"`
+}
+
+const selectCourseQuery = `
+SELECT id, prereq_tree, coreq_tree FROM course WHERE code = $1
+`
+
+const selectAntireqsQuery = `
+SELECT a.code
+FROM course_antirequisite ca
+  JOIN course a ON a.id = ca.antirequisite_id
+WHERE ca.course_id = $1
+ORDER BY a.code
+`
+
+// Transfer credits have no term and are always completed
+const selectTakenQuery = `
+SELECT c.code, uct.term_id
+FROM user_course_taken uct
+  JOIN course c ON c.id = uct.course_id
+WHERE uct.user_id = $1
+`
+
+func scanCodes(tx *db.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying courses: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		err = rows.Scan(&code)
+		if err != nil {
+			return nil, fmt.Errorf("reading course code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// selectTaken returns the courses completed by the user before the current term,
+// which satisfy prereqs, and all courses taken, including those of the current term
+// and later, which satisfy coreqs and are in the way of antireqs.
+func selectTaken(tx *db.Tx, userId int) (map[string]bool, map[string]bool, error) {
+	rows, err := tx.Query(selectTakenQuery, userId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying courses taken: %w", err)
+	}
+	defer rows.Close()
+
+	currentTermId := util.CurrentTermId()
+	completed := make(map[string]bool)
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var code string
+		var termId *int
+		err = rows.Scan(&code, &termId)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading course taken: %w", err)
+		}
+		if termId == nil || *termId < currentTermId {
+			completed[code] = true
+		}
+		taken[code] = true
+	}
+	return completed, taken, nil
+}
+
+// evaluate checks the prereqs of a course against the courses completed,
+// and its coreqs and antireqs against all courses taken.
+// Courses without requisite trees have no requirements that we can check.
+func evaluate(prereqTree, coreqTree *requirement.Tree, antireqs []string, completed, taken map[string]bool) eligibilityResponse {
+	response := eligibilityResponse{
+		UnmetPrereqs:  []string{},
+		UnmetCoreqs:   []string{},
+		TakenAntireqs: []string{},
+	}
+	if prereqTree != nil {
+		_, unmet := prereqTree.Evaluate(completed)
+		response.UnmetPrereqs = append(response.UnmetPrereqs, unmet...)
+	}
+	if coreqTree != nil {
+		_, unmet := coreqTree.Evaluate(taken)
+		response.UnmetCoreqs = append(response.UnmetCoreqs, unmet...)
+	}
+	for _, code := range antireqs {
+		if taken[code] {
+			response.TakenAntireqs = append(response.TakenAntireqs, strings.ToUpper(code))
+		}
+	}
+	response.Eligible = len(response.UnmetPrereqs) == 0 &&
+		len(response.UnmetCoreqs) == 0 &&
+		len(response.TakenAntireqs) == 0
+	return response
+}
+
+// HandleEligibility checks whether the user may take a course given the courses they took.
+func HandleEligibility(tx *db.Tx, r *http.Request) (interface{}, error) {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	// Codes are stored in lowercase without spaces, e.g. cs135
+	courseCode := strings.ToLower(strings.ReplaceAll(chi.URLParam(r, "courseCode"), " ", ""))
+
+	var courseId int
+	var prereqTree, coreqTree *requirement.Tree
+	err = tx.QueryRow(selectCourseQuery, courseCode).Scan(&courseId, &prereqTree, &coreqTree)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, serde.WithStatus(http.StatusNotFound, fmt.Errorf("no course %s", courseCode))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying course: %w", err)
+	}
+
+	antireqs, err := scanCodes(tx, selectAntireqsQuery, courseId)
+	if err != nil {
+		return nil, err
+	}
+	completed, taken, err := selectTaken(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := evaluate(prereqTree, coreqTree, antireqs, completed, taken)
+	response.CourseCode = courseCode
+	return response, nil
+}
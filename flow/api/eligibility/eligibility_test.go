@@ -0,0 +1,87 @@
+package eligibility
+
+import (
+	"testing"
+
+	"flow/common/requirement"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvaluate(t *testing.T) {
+	course := requirement.Course
+	// Prerequisites of CS 240 and a made-up corequisite
+	prereqTree := requirement.And(
+		requirement.Or(course("cs136"), course("cs146")),
+		requirement.Or(course("math135"), course("math145")),
+	)
+	coreqTree := course("stat230")
+	antireqs := []string{"cs240e"}
+
+	tests := []struct {
+		name string
+		// Courses completed before the current term
+		completed []string
+		// Courses of the current term
+		inProgress []string
+		want       eligibilityResponse
+	}{
+		{
+			"eligible",
+			[]string{"cs146", "math135", "stat230"},
+			nil,
+			eligibilityResponse{
+				Eligible:      true,
+				UnmetPrereqs:  []string{},
+				UnmetCoreqs:   []string{},
+				TakenAntireqs: []string{},
+			},
+		},
+		{
+			"unmet",
+			[]string{"cs136", "cs240e"},
+			nil,
+			eligibilityResponse{
+				Eligible:      false,
+				UnmetPrereqs:  []string{"MATH135 or MATH145"},
+				UnmetCoreqs:   []string{"STAT230"},
+				TakenAntireqs: []string{"CS240E"},
+			},
+		},
+		{
+			// Courses in progress satisfy coreqs, but not prereqs
+			"in_progress",
+			[]string{"cs146"},
+			[]string{"math135", "stat230"},
+			eligibilityResponse{
+				Eligible:      false,
+				UnmetPrereqs:  []string{"MATH135 or MATH145"},
+				UnmetCoreqs:   []string{},
+				TakenAntireqs: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			completed := make(map[string]bool)
+			taken := make(map[string]bool)
+			for _, code := range tt.completed {
+				completed[code] = true
+				taken[code] = true
+			}
+			for _, code := range tt.inProgress {
+				taken[code] = true
+			}
+			got := evaluate(prereqTree, coreqTree, antireqs, completed, taken)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+
+	got := evaluate(nil, nil, nil, nil, nil)
+	if !got.Eligible {
+		t.Errorf("Expected a course without requisites to be eligible, but got %+v", got)
+	}
+}
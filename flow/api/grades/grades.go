@@ -0,0 +1,125 @@
+package grades
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"flow/api/serde"
+	"flow/common/db"
+)
+
+// gradeRow is a single course from secret.user_course_grade.
+type gradeRow struct {
+	TermId           int
+	AttemptedCredits float64
+	EarnedCredits    float64
+	Grade            *string
+	InAverage        bool
+}
+
+type termAverage struct {
+	TermId int `json:"term_id"`
+	// Average is nil if no course taken in the term has a numeric grade.
+	Average *float64 `json:"average"`
+	Credits float64  `json:"credits"`
+}
+
+type averagesResponse struct {
+	Terms      []termAverage `json:"terms"`
+	Cumulative *float64      `json:"cumulative"`
+}
+
+const selectGradesQuery = `
+SELECT term_id, attempted_credits, earned_credits, grade, in_average
+FROM secret.user_course_grade
+WHERE user_id = $1
+ORDER BY term_id
+`
+
+// round rounds to two decimal places, which is the precision of transcripts.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// weightedAverage is a running credit-weighted average.
+type weightedAverage struct {
+	sum     float64
+	credits float64
+}
+
+func (a *weightedAverage) add(grade, credits float64) {
+	a.sum += grade * credits
+	a.credits += credits
+}
+
+func (a *weightedAverage) value() *float64 {
+	if a.credits == 0 {
+		return nil
+	}
+	average := round(a.sum / a.credits)
+	return &average
+}
+
+// computeAverages computes term and cumulative averages the way the registrar does:
+// only courses with a numeric grade that count towards the average are included,
+// each weighted by its attempted credits so that failed courses still count.
+// Rows are expected to be sorted by term.
+func computeAverages(rows []gradeRow) averagesResponse {
+	response := averagesResponse{Terms: []termAverage{}}
+	var cumulative weightedAverage
+
+	for i := 0; i < len(rows); {
+		termId := rows[i].TermId
+		var term weightedAverage
+		var credits float64
+		for ; i < len(rows) && rows[i].TermId == termId; i++ {
+			row := rows[i]
+			credits += row.EarnedCredits
+			if !row.InAverage || row.Grade == nil {
+				continue
+			}
+			// Non-numeric grades such as CR or NCR are not averaged
+			grade, err := strconv.ParseFloat(*row.Grade, 64)
+			if err != nil {
+				continue
+			}
+			term.add(grade, row.AttemptedCredits)
+			cumulative.add(grade, row.AttemptedCredits)
+		}
+		response.Terms = append(response.Terms, termAverage{
+			TermId:  termId,
+			Average: term.value(),
+			Credits: credits,
+		})
+	}
+
+	response.Cumulative = cumulative.value()
+	return response
+}
+
+func HandleAverages(tx *db.Tx, r *http.Request) (interface{}, error) {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	rows, err := tx.Query(selectGradesQuery, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying grades: %w", err)
+	}
+	defer rows.Close()
+
+	var grades []gradeRow
+	for rows.Next() {
+		var row gradeRow
+		err = rows.Scan(&row.TermId, &row.AttemptedCredits, &row.EarnedCredits, &row.Grade, &row.InAverage)
+		if err != nil {
+			return nil, fmt.Errorf("reading grade row: %w", err)
+		}
+		grades = append(grades, row)
+	}
+
+	return computeAverages(grades), nil
+}
@@ -0,0 +1,48 @@
+package grades
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func grade(value string) *string {
+	return &value
+}
+
+func average(value float64) *float64 {
+	return &value
+}
+
+func TestComputeAverages(t *testing.T) {
+	rows := []gradeRow{
+		{1179, 0.5, 0.5, grade("98"), true},
+		{1179, 0.5, 0.5, grade("85"), true},
+		{1179, 0.5, 0.5, grade("CR"), true},
+		{1179, 0.5, 0.5, grade("60"), false},
+		{1181, 0.5, 0.0, grade("42"), true},
+		{1181, 0.25, 0.25, grade("90"), true},
+		{1185, 0.5, 0.0, nil, true},
+	}
+	want := averagesResponse{
+		Terms: []termAverage{
+			{TermId: 1179, Average: average(91.5), Credits: 2.0},
+			{TermId: 1181, Average: average(58), Credits: 0.25},
+			{TermId: 1185, Average: nil, Credits: 0},
+		},
+		// (98*0.5 + 85*0.5 + 42*0.5 + 90*0.25) / 1.75
+		Cumulative: average(77.14),
+	}
+
+	got := computeAverages(rows)
+	if !cmp.Equal(want, got) {
+		t.Errorf("averages mismatch (-want +got):\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestComputeAveragesEmpty(t *testing.T) {
+	got := computeAverages(nil)
+	if len(got.Terms) != 0 || got.Cumulative != nil {
+		t.Errorf("Expected no averages, but got %+v", got)
+	}
+}
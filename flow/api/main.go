@@ -7,12 +7,17 @@ import (
 	"time"
 	_ "time/tzdata"
 
+	"flow/api/audit"
 	"flow/api/auth"
 	"flow/api/calendar"
 	"flow/api/data"
+	"flow/api/eligibility"
 	"flow/api/env"
+	"flow/api/grades"
 	"flow/api/middleware"
 	"flow/api/parse"
+	"flow/api/parse/pdf"
+	"flow/api/planner"
 	"flow/api/serde"
 
 	"flow/common/db"
@@ -75,22 +80,70 @@ func setupRouter(conn *db.Conn) *chi.Mux {
 
 	router.Post(
 		"/parse/transcript",
-		serde.WithDbResponse(conn, parse.HandleTranscript, "transcript upload"),
+		serde.WithDbResponse(conn, parse.HandleTranscript(conn), "transcript upload"),
 	)
 	router.Post(
 		"/parse/schedule",
-		serde.WithDbResponse(conn, parse.HandleSchedule, "schedule upload"),
+		serde.WithDbResponse(conn, parse.HandleSchedule(conn), "schedule upload"),
 	)
 
 	router.Get(
 		"/data/search",
-		serde.WithDbResponse(conn, data.HandleSearch, "search data dump"),
+		serde.WithDbResponse(conn, data.HandleSearch, "course and prof search"),
+	)
+
+	router.Get(
+		"/grades/averages",
+		serde.WithDbResponse(conn, grades.HandleAverages, "grade averages"),
+	)
+
+	router.Get(
+		"/eligibility/{courseCode}",
+		serde.WithDbResponse(conn, eligibility.HandleEligibility, "eligibility check"),
+	)
+
+	auditHandler, err := audit.HandleAudit()
+	if err != nil {
+		// Audits fail, but the rest of the API keeps working
+		log.Printf("Error: %s", err)
+		auditHandler = func(*db.Tx, *http.Request) (interface{}, error) {
+			return nil, err
+		}
+	}
+	router.Get(
+		"/audit",
+		serde.WithDbResponse(conn, auditHandler, "degree audit"),
+	)
+
+	router.Get(
+		"/planner",
+		serde.WithDbResponse(conn, planner.HandlePlanner, "timetable planning"),
 	)
 
 	router.Get(
 		"/calendar/{secretId}.ics",
 		serde.WithDbDirect(conn, calendar.HandleCalendar, "calendar generation"),
 	)
+	router.Get(
+		"/calendar/section/{termId}/{classNumber}.ics",
+		serde.WithDbDirect(conn, calendar.HandleSectionCalendar, "section calendar generation"),
+	)
+	router.Get(
+		"/calendar/course/{termId}/{courseCode}.ics",
+		serde.WithDbDirect(conn, calendar.HandleCourseCalendar, "course calendar generation"),
+	)
+	router.Post(
+		"/calendar/rotate",
+		serde.WithDbResponse(conn, calendar.RotateSecretId, "calendar secret rotation"),
+	)
+	router.Post(
+		"/calendar/enable",
+		serde.WithDbNoResponse(conn, calendar.EnableFeed, "calendar enabling"),
+	)
+	router.Post(
+		"/calendar/disable",
+		serde.WithDbNoResponse(conn, calendar.DisableFeed, "calendar disabling"),
+	)
 
 	router.Delete(
 		"/user",
@@ -101,6 +154,11 @@ func setupRouter(conn *db.Conn) *chi.Mux {
 }
 
 func main() {
+	// This binary doubles as the sandboxed worker that converts PDFs
+	if pdf.IsWorker() {
+		pdf.ServeWorker()
+	}
+
 	env.Init()
 	conn, err := db.ConnectPool(context.Background(), &env.Global)
 	if err != nil {
@@ -0,0 +1,51 @@
+// Package diagnostic describes how well a parser understood its input.
+// When Quest changes its formats, parsers tend to silently drop data
+// instead of failing, so they report what looked like data but was not parsed.
+package diagnostic
+
+import "fmt"
+
+// Each mismatch makes us this much less confident in the result.
+const mismatchPenalty = 0.75
+
+type Report struct {
+	// UnmatchedLines look like they hold data, but were not parsed.
+	UnmatchedLines []string `json:"unmatched_lines"`
+	// Mismatches are between counts that should agree,
+	// e.g. the number of terms and academic levels on a transcript.
+	Mismatches []string `json:"mismatches"`
+	// Matched is the number of items, such as courses, that were parsed.
+	Matched int `json:"matched"`
+	// Confidence is between 0 (no trust) and 1 (complete trust) in the result.
+	// It is only meaningful once Score has been called.
+	Confidence float64 `json:"confidence"`
+}
+
+func (r *Report) Unmatched(line string) {
+	r.UnmatchedLines = append(r.UnmatchedLines, line)
+}
+
+func (r *Report) Mismatch(format string, args ...interface{}) {
+	r.Mismatches = append(r.Mismatches, fmt.Sprintf(format, args...))
+}
+
+// Score computes the confidence as the fraction of items that were parsed,
+// taking every unmatched line to be an item, with a penalty per mismatch.
+// A result without any items cannot be trusted at all.
+func (r *Report) Score() {
+	total := r.Matched + len(r.UnmatchedLines)
+	if r.Matched == 0 {
+		r.Confidence = 0
+		return
+	}
+	r.Confidence = float64(r.Matched) / float64(total)
+	for range r.Mismatches {
+		r.Confidence *= mismatchPenalty
+	}
+}
+
+// Fail marks the result as untrustworthy, as parsing failed altogether.
+func (r *Report) Fail(err error) {
+	r.Mismatch("parsing failed: %v", err)
+	r.Confidence = 0
+}
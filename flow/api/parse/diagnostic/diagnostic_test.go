@@ -0,0 +1,47 @@
+package diagnostic
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		matched    int
+		unmatched  int
+		mismatches int
+		want       float64
+	}{
+		{"perfect", 10, 0, 0, 1},
+		{"unmatched", 9, 1, 0, 0.9},
+		{"mismatch", 10, 0, 1, 0.75},
+		{"both", 3, 1, 2, 0.421875},
+		{"empty", 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Report{Matched: tt.matched}
+			for i := 0; i < tt.unmatched; i++ {
+				report.Unmatched("line")
+			}
+			for i := 0; i < tt.mismatches; i++ {
+				report.Mismatch("%d != %d", i, i+1)
+			}
+			report.Score()
+			if math.Abs(report.Confidence-tt.want) > 1e-9 {
+				t.Errorf("Expected confidence %v, but got %v", tt.want, report.Confidence)
+			}
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+	report := Report{Matched: 10}
+	report.Score()
+	report.Fail(errors.New("term id not found"))
+	if report.Confidence != 0 || len(report.Mismatches) != 1 {
+		t.Errorf("Expected zero confidence and a mismatch, but got %+v", report)
+	}
+}
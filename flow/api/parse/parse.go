@@ -3,10 +3,13 @@ package parse
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"flow/api/parse/diagnostic"
 	"flow/api/parse/pdf"
 	"flow/api/parse/schedule"
 	"flow/api/parse/transcript"
@@ -16,7 +19,9 @@ import (
 )
 
 type transcriptResponse struct {
-	CoursesImported int `json:"courses_imported"`
+	CoursesImported         int                `json:"courses_imported"`
+	TransferCreditsImported int                `json:"transfer_credits_imported"`
+	Diagnostics             *diagnostic.Report `json:"diagnostics"`
 }
 
 const updateProgramQuery = `
@@ -29,32 +34,112 @@ WHERE term_id <= $1 AND user_id = $2
 `
 
 const insertTranscriptQuery = `
-INSERT INTO user_course_taken(course_id, user_id, term_id, level)
-SELECT id, $2, $3, $4 FROM course WHERE code = $1
+INSERT INTO user_course_taken(course_id, user_id, term_id, level, earned_units)
+SELECT id, $2, $3, $4, $5 FROM course WHERE code = $1
 `
 
-func saveTranscript(tx *db.Tx, summary *transcript.Summary, userId int) (*transcriptResponse, error) {
+// Transfer credits have no term. The transcript lists all of them,
+// so we can always replace the previously imported ones.
+const deleteTransferCreditsQuery = `
+DELETE FROM user_course_taken
+WHERE term_id IS NULL AND user_id = $1
+`
+
+const insertTransferCreditQuery = `
+INSERT INTO user_course_taken(course_id, user_id, term_id, level, earned_units)
+SELECT id, $2, NULL, NULL, $3 FROM course WHERE code = $1
+ON CONFLICT DO NOTHING
+`
+
+// earnedUnits returns the units earned in a course, which are not known
+// until it is graded: courses in progress are listed as earning nothing.
+func earnedUnits(course *transcript.Course) *float64 {
+	if course.Grade == "" {
+		return nil
+	}
+	units := course.EarnedCredits
+	return &units
+}
+
+const deleteGradesQuery = `
+DELETE FROM secret.user_course_grade
+WHERE term_id <= $1 AND user_id = $2
+`
+
+const insertGradeQuery = `
+INSERT INTO secret.user_course_grade(
+  user_id, term_id, course_code, title,
+  attempted_credits, earned_credits, grade, in_average
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT DO NOTHING
+`
+
+// Importing without saving grades withdraws consent to keep them
+const deleteAllGradesQuery = `
+DELETE FROM secret.user_course_grade
+WHERE user_id = $1
+`
+
+// saveGrades replaces the grades of the user with those on the transcript.
+// This should only be done if the user has opted in.
+func saveGrades(tx *db.Tx, summary *transcript.Summary, userId int) error {
+	_, err := tx.Exec(deleteGradesQuery, maxTermId(summary), userId)
+	if err != nil {
+		return fmt.Errorf("deleting old grades: %w", err)
+	}
+
+	for _, termSummary := range summary.TermSummaries {
+		for _, course := range termSummary.Courses {
+			_, err = tx.Exec(
+				insertGradeQuery,
+				userId, termSummary.TermId, course.Code, course.Title,
+				course.AttemptedCredits, course.EarnedCredits, util.NilIfEmpty(course.Grade), course.InAverage,
+			)
+			if err != nil {
+				return fmt.Errorf("writing user_course_grade: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateTranscript(summary *transcript.Summary) error {
 	// Refuse to import empty transcript: we probably failed to parse it correctly
 	if len(summary.TermSummaries) == 0 {
-		return nil, serde.WithStatus(
+		return serde.WithStatus(
 			http.StatusBadRequest,
 			serde.WithEnum(serde.EmptyTranscript, fmt.Errorf("empty transcript")),
 		)
 	}
+	return nil
+}
 
-	_, err := tx.Exec(updateProgramQuery, summary.ProgramName, userId)
-	if err != nil {
-		return nil, fmt.Errorf("updating user program: %w", err)
-	}
-
+// maxTermId returns the last term on the transcript.
+// Courses taken up to and including it are replaced by those on the transcript.
+func maxTermId(summary *transcript.Summary) int {
 	var maxTermId int
 	for _, termSummary := range summary.TermSummaries {
 		if termSummary.TermId > maxTermId {
 			maxTermId = termSummary.TermId
 		}
 	}
+	return maxTermId
+}
+
+func saveTranscript(tx *db.Tx, summary *transcript.Summary, userId int) (*transcriptResponse, error) {
+	err := validateTranscript(summary)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = tx.Exec(deleteTranscriptQuery, maxTermId, userId)
+	_, err = tx.Exec(updateProgramQuery, summary.ProgramName, userId)
+	if err != nil {
+		return nil, fmt.Errorf("updating user program: %w", err)
+	}
+
+	_, err = tx.Exec(deleteTranscriptQuery, maxTermId(summary), userId)
 	if err != nil {
 		return nil, fmt.Errorf("deleting old courses: %w", err)
 	}
@@ -62,53 +147,180 @@ func saveTranscript(tx *db.Tx, summary *transcript.Summary, userId int) (*transc
 	var response transcriptResponse
 	for _, termSummary := range summary.TermSummaries {
 		response.CoursesImported += len(termSummary.Courses)
-		for _, course := range termSummary.Courses {
-			_, err = tx.Exec(insertTranscriptQuery, course, userId, termSummary.TermId, termSummary.Level)
+		for i := range termSummary.Courses {
+			course := &termSummary.Courses[i]
+			_, err = tx.Exec(
+				insertTranscriptQuery,
+				course.Code, userId, termSummary.TermId, termSummary.Level, earnedUnits(course),
+			)
 			if err != nil {
 				return nil, fmt.Errorf("updating user_course_taken: %w", err)
 			}
 		}
 	}
 
+	_, err = tx.Exec(deleteTransferCreditsQuery, userId)
+	if err != nil {
+		return nil, fmt.Errorf("deleting old transfer credits: %w", err)
+	}
+
+	response.TransferCreditsImported = len(summary.TransferCredits)
+	for _, course := range summary.TransferCredits {
+		_, err = tx.Exec(insertTransferCreditQuery, course.Code, userId, course.EarnedCredits)
+		if err != nil {
+			return nil, fmt.Errorf("updating user_course_taken: %w", err)
+		}
+	}
+
 	return &response, nil
 }
 
-func HandleTranscript(tx *db.Tx, r *http.Request) (interface{}, error) {
-	userId, err := serde.UserIdFromRequest(r)
-	if err != nil {
-		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
-	}
+// Multipart form fields other than the file itself are small.
+const maxFormOverhead = 1 << 20
 
+// transcriptText converts the uploaded transcript to text in a sandboxed worker.
+func transcriptText(r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, pdf.MaxSize+maxFormOverhead)
 	file, header, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && header.Size > pdf.MaxSize) {
+		return "", serde.WithStatus(
+			http.StatusRequestEntityTooLarge,
+			serde.WithEnum(serde.TranscriptTooLarge, fmt.Errorf("transcript exceeds %d bytes", pdf.MaxSize)),
+		)
+	}
 	if err != nil {
-		return nil, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("expected form/multipart: {file}"))
+		return "", serde.WithStatus(http.StatusBadRequest, fmt.Errorf("expected form/multipart: {file}"))
 	}
 
 	var fileContents bytes.Buffer
 	fileContents.Grow(int(header.Size))
 	fileContents.ReadFrom(file)
-	text, err := pdf.ToText(fileContents.Bytes())
+	text, err := pdf.ToTextSandboxed(r.Context(), fileContents.Bytes())
+	if errors.Is(err, pdf.ErrTimeout) || errors.Is(err, pdf.ErrKilled) {
+		return "", serde.WithStatus(
+			http.StatusUnprocessableEntity,
+			serde.WithEnum(serde.TranscriptConversionFailed, fmt.Errorf("converting to text: %w", err)),
+		)
+	}
+	if err != nil {
+		return "", serde.WithStatus(http.StatusBadRequest, fmt.Errorf("converting to text: %w", err))
+	}
+	return text, nil
+}
+
+// parseFlag reads an optional boolean form value, which is false if absent.
+func parseFlag(r *http.Request, name string) (bool, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return false, nil
+	}
+	flag, err := strconv.ParseBool(value)
 	if err != nil {
-		return nil, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("converting to text: %w", err))
+		return false, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing %s: %w", name, err))
+	}
+	return flag, nil
+}
+
+// HandleTranscript imports transcripts. Those that could not be parsed
+// with confidence are saved for review through conn if the user consents.
+func HandleTranscript(conn *db.Conn) func(*db.Tx, *http.Request) (interface{}, error) {
+	return func(tx *db.Tx, r *http.Request) (interface{}, error) {
+		return handleTranscript(conn.With(r.Context()), tx, r)
+	}
+}
+
+func handleTranscript(conn *db.Conn, tx *db.Tx, r *http.Request) (interface{}, error) {
+	userId, err := serde.UserIdFromRequest(r)
+	if err != nil {
+		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
+	}
+
+	preview, err := isPreview(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := transcriptText(r)
+	if err != nil {
+		return nil, err
 	}
 
-	summary, err := transcript.Parse(text)
+	// Grades are sensitive, so we only keep them if explicitly asked to,
+	// and forget any kept before otherwise
+	shouldSaveGrades, err := parseFlag(r, "save_grades")
+	if err != nil {
+		return nil, err
+	}
+	shareForReview, err := parseFlag(r, "share_for_review")
+	if err != nil {
+		return nil, err
+	}
+
+	summary, report, err := transcript.Parse(text)
+	if shareForReview && needsReview(report) {
+		saveReview(conn, "transcript", transcript.Anonymize(text), report)
+	}
 	if err != nil {
 		return nil, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing: %w", err))
 	}
 
+	if preview {
+		response, err := previewTranscript(tx, summary, userId)
+		if err != nil {
+			return nil, err
+		}
+		response.Diagnostics = report
+		return response, nil
+	}
+
 	response, err := saveTranscript(tx, summary, userId)
 	if err != nil {
 		return nil, err
 	}
+	response.Diagnostics = report
+
+	if shouldSaveGrades {
+		err = saveGrades(tx, summary, userId)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		_, err = tx.Exec(deleteAllGradesQuery, userId)
+		if err != nil {
+			return nil, fmt.Errorf("deleting grades: %w", err)
+		}
+	}
 
-	log.Printf("Imported transcript for user %d: %+v", userId, summary)
+	// The summary has grades, so only its size is logged
+	courseCount := 0
+	for _, term := range summary.TermSummaries {
+		courseCount += len(term.Courses)
+	}
+	log.Printf(
+		"Imported transcript for user %d: %d terms, %d courses, %d transfer credits",
+		userId, len(summary.TermSummaries), courseCount, len(summary.TransferCredits),
+	)
 	return response, nil
 }
 
-type scheduleResponse struct {
+type termScheduleResponse struct {
+	TermId           int   `json:"term_id"`
 	SectionsImported int   `json:"sections_imported"`
 	FailedClasses    []int `json:"failed_classes"`
+	// Differences between the pasted schedule and the imported sections
+	Warnings []scheduleWarning `json:"warnings"`
+}
+
+// scheduleResponse has the results for each term on the schedule,
+// as well as their totals, which are all there is to a single term.
+type scheduleResponse struct {
+	SectionsImported int                    `json:"sections_imported"`
+	FailedClasses    []int                  `json:"failed_classes"`
+	Warnings         []scheduleWarning      `json:"warnings"`
+	Terms            []termScheduleResponse `json:"terms"`
+	// How well the schedule was understood
+	Diagnostics *diagnostic.Report `json:"diagnostics"`
 }
 
 const deleteCourseTakenQuery = `
@@ -137,10 +349,10 @@ SELECT $1, id, $4 FROM course_section
 WHERE class_number = $2 AND term_id = $3
 `
 
-func saveSchedule(tx *db.Tx, summary *schedule.Summary, userId int) (*scheduleResponse, error) {
+func validateSchedule(summary *schedule.Summary) error {
 	// Refuse to import old schedule: there are no sections in database, so we will fail
 	if summary.TermId < util.CurrentTermId() {
-		return nil, serde.WithStatus(
+		return serde.WithStatus(
 			http.StatusBadRequest,
 			serde.WithEnum(serde.OldSchedule, fmt.Errorf("term %d has passed", summary.TermId)),
 		)
@@ -148,12 +360,16 @@ func saveSchedule(tx *db.Tx, summary *schedule.Summary, userId int) (*scheduleRe
 
 	// Refuse to import empty schedule: we probably failed to parse it
 	if len(summary.Classes) == 0 {
-		return nil, serde.WithStatus(
+		return serde.WithStatus(
 			http.StatusBadRequest,
 			serde.WithEnum(serde.EmptySchedule, fmt.Errorf("empty schedule")),
 		)
 	}
+	return nil
+}
 
+// saveSchedule replaces the schedule of the user for the term of the summary.
+func saveSchedule(tx *db.Tx, summary *schedule.Summary, userId int) (*termScheduleResponse, error) {
 	_, err := tx.Exec(deleteCourseTakenQuery, userId, summary.TermId)
 	if err != nil {
 		return nil, fmt.Errorf("deleting old user_course_taken: %w", err)
@@ -186,35 +402,103 @@ func saveSchedule(tx *db.Tx, summary *schedule.Summary, userId int) (*scheduleRe
 		}
 	}
 
-	return &scheduleResponse{SectionsImported: len(summary.Classes), FailedClasses: failedClasses}, nil
+	warnings, err := scheduleWarnings(tx, summary)
+	if err != nil {
+		return nil, fmt.Errorf("comparing with section meetings: %w", err)
+	}
+
+	return &termScheduleResponse{
+		TermId:           summary.TermId,
+		SectionsImported: len(summary.Classes),
+		FailedClasses:    failedClasses,
+		Warnings:         warnings,
+	}, nil
+}
+
+// saveSchedules replaces the schedules of all terms in the same transaction.
+// They are all validated first, so that none are saved if any is invalid.
+func saveSchedules(tx *db.Tx, summaries []*schedule.Summary, userId int) (*scheduleResponse, error) {
+	for _, summary := range summaries {
+		err := validateSchedule(summary)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var response scheduleResponse
+	for _, summary := range summaries {
+		termResponse, err := saveSchedule(tx, summary, userId)
+		if err != nil {
+			return nil, fmt.Errorf("term %d: %w", summary.TermId, err)
+		}
+		response.SectionsImported += termResponse.SectionsImported
+		response.FailedClasses = append(response.FailedClasses, termResponse.FailedClasses...)
+		response.Warnings = append(response.Warnings, termResponse.Warnings...)
+		response.Terms = append(response.Terms, *termResponse)
+	}
+	return &response, nil
 }
 
 type scheduleRequest struct {
 	Text string `json:"text"`
+	// Whether the user consents to us keeping the anonymized schedule
+	// if we could not parse it with confidence
+	ShareForReview bool `json:"share_for_review"`
 }
 
-func HandleSchedule(tx *db.Tx, r *http.Request) (interface{}, error) {
+// HandleSchedule imports schedules. Those that could not be parsed
+// with confidence are saved for review through conn if the user consents.
+func HandleSchedule(conn *db.Conn) func(*db.Tx, *http.Request) (interface{}, error) {
+	return func(tx *db.Tx, r *http.Request) (interface{}, error) {
+		return handleSchedule(conn.With(r.Context()), tx, r)
+	}
+}
+
+func handleSchedule(conn *db.Conn, tx *db.Tx, r *http.Request) (interface{}, error) {
 	userId, err := serde.UserIdFromRequest(r)
 	if err != nil {
 		return nil, serde.WithStatus(http.StatusUnauthorized, fmt.Errorf("extracting user id: %w", err))
 	}
 
+	preview, err := isPreview(r)
+	if err != nil {
+		return nil, err
+	}
+
 	var req scheduleRequest
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		return nil, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("malformed JSON: %w", err))
 	}
 
-	summary, err := schedule.Parse(req.Text)
+	summaries, report, err := schedule.Parse(req.Text)
+	if req.ShareForReview && needsReview(report) {
+		if anonymized, ok := schedule.Anonymize(req.Text); ok {
+			saveReview(conn, "schedule", anonymized, report)
+		}
+	}
 	if err != nil {
 		return nil, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing: %w", err))
 	}
 
-	response, err := saveSchedule(tx, summary, userId)
+	if preview {
+		response, err := previewSchedules(tx, summaries, userId)
+		if err != nil {
+			return nil, err
+		}
+		response.Diagnostics = report
+		return response, nil
+	}
+
+	response, err := saveSchedules(tx, summaries, userId)
 	if err != nil {
 		return nil, fmt.Errorf("saving: %w", err)
 	}
+	response.Diagnostics = report
 
-	log.Printf("Imported schedule for user %d: %+v", userId, summary)
+	// Classes have instructors, locations and times, so only their number is logged
+	for _, summary := range summaries {
+		log.Printf("Imported schedule for user %d: term %d, %d classes", userId, summary.TermId, len(summary.Classes))
+	}
 	return response, nil
 }
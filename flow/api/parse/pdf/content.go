@@ -0,0 +1,361 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Form XObjects may draw other forms, but not arbitrarily deep.
+const maxFormDepth = 8
+
+// matrix is [a b c d e f], which stands for the affine transformation
+//
+//	| a b 0 |
+//	| c d 0 |
+//	| e f 1 |
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n, which applies m and then n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translation(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// textState holds the text state parameters (ISO 32000-1, section 9.3).
+type textState struct {
+	font       *font
+	size       float64
+	charSpace  float64
+	wordSpace  float64
+	scale      float64
+	leading    float64
+	rise       float64
+	lineMatrix matrix
+	textMatrix matrix
+}
+
+// glyph is a character as placed on the page, in device space.
+type glyph struct {
+	x, y  float64
+	width float64
+	size  float64
+	text  string
+}
+
+// interpreter runs content streams and collects the glyphs that they show.
+type interpreter struct {
+	reader *reader
+	fonts  map[ref]*font
+	glyphs []glyph
+}
+
+func newInterpreter(r *reader) *interpreter {
+	return &interpreter{reader: r, fonts: make(map[ref]*font)}
+}
+
+func (in *interpreter) font(resources dict, fontName name) (*font, error) {
+	fonts, err := in.reader.resolveDict(resources["Font"])
+	if err != nil {
+		return nil, fmt.Errorf("reading fonts: %w", err)
+	}
+	// Text in a missing font cannot be extracted, but the rest of the page can
+	o, ok := fonts[fontName]
+	if !ok {
+		return nil, nil
+	}
+	if ref, ok := o.(ref); ok {
+		if f, ok := in.fonts[ref]; ok {
+			return f, nil
+		}
+		f, err := in.reader.loadFont(ref)
+		if err != nil {
+			return nil, fmt.Errorf("loading font %s: %w", fontName, err)
+		}
+		in.fonts[ref] = f
+		return f, nil
+	}
+	return in.reader.loadFont(o)
+}
+
+// pageContents concatenates the content streams of a page.
+func (in *interpreter) pageContents(contents object) ([]byte, error) {
+	value, err := in.reader.resolve(contents)
+	if err != nil {
+		return nil, err
+	}
+	var streams array
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *stream:
+		streams = array{v}
+	case array:
+		streams = v
+	default:
+		return nil, fmt.Errorf("invalid page contents: %T", value)
+	}
+
+	var result bytes.Buffer
+	for _, item := range streams {
+		value, err := in.reader.resolve(item)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := value.(*stream)
+		if !ok {
+			continue
+		}
+		data, err := in.reader.decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if result.Len()+len(data) > maxStreamSize {
+			return nil, fmt.Errorf("page contents exceed %d bytes", maxStreamSize)
+		}
+		// Streams are split at arbitrary token boundaries, so separate them
+		result.Write(data)
+		result.WriteByte('\n')
+	}
+	return result.Bytes(), nil
+}
+
+// run interprets a content stream with the given resources and initial CTM.
+// Only operators that affect the position or content of text are implemented.
+func (in *interpreter) run(data []byte, resources dict, ctm matrix, depth int) error {
+	type graphicsState struct {
+		ctm  matrix
+		text textState
+	}
+	state := graphicsState{ctm: ctm, text: textState{scale: 1}}
+	var stack []graphicsState
+	var operands []object
+
+	p := &parser{data: data}
+	for !p.atEnd() {
+		value, err := p.object()
+		if err != nil {
+			return err
+		}
+		operator, ok := value.(keyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+
+		ts := &state.text
+		switch operator {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := matrixOperand(operands); ok {
+				state.ctm = m.multiply(state.ctm)
+			}
+		case "BT":
+			ts.lineMatrix = identity
+			ts.textMatrix = identity
+		case "Tf":
+			if len(operands) == 2 {
+				fontName, _ := operands[0].(name)
+				ts.size, _ = number(operands[1])
+				ts.font, err = in.font(resources, fontName)
+				if err != nil {
+					return err
+				}
+			}
+		case "Tc":
+			ts.charSpace = numberOperand(operands, 0)
+		case "Tw":
+			ts.wordSpace = numberOperand(operands, 0)
+		case "Tz":
+			ts.scale = numberOperand(operands, 0) / 100
+		case "TL":
+			ts.leading = numberOperand(operands, 0)
+		case "Ts":
+			ts.rise = numberOperand(operands, 0)
+		case "Td", "TD":
+			x, y := numberOperand(operands, 0), numberOperand(operands, 1)
+			if operator == "TD" {
+				ts.leading = -y
+			}
+			ts.lineMatrix = translation(x, y).multiply(ts.lineMatrix)
+			ts.textMatrix = ts.lineMatrix
+		case "Tm":
+			if m, ok := matrixOperand(operands); ok {
+				ts.lineMatrix = m
+				ts.textMatrix = m
+			}
+		case "T*":
+			ts.nextLine()
+		case "Tj":
+			if len(operands) == 1 {
+				in.show(ts, state.ctm, operands[0])
+			}
+		case "'":
+			ts.nextLine()
+			if len(operands) == 1 {
+				in.show(ts, state.ctm, operands[0])
+			}
+		case "\"":
+			if len(operands) == 3 {
+				ts.wordSpace = numberOperand(operands, 0)
+				ts.charSpace = numberOperand(operands, 1)
+				ts.nextLine()
+				in.show(ts, state.ctm, operands[2])
+			}
+		case "TJ":
+			if len(operands) == 1 {
+				items, _ := operands[0].(array)
+				for _, item := range items {
+					if adjustment, ok := number(item); ok {
+						// Adjustments are in thousandths of text space units, subtracted from x
+						tx := -adjustment / 1000 * ts.size * ts.scale
+						ts.textMatrix = translation(tx, 0).multiply(ts.textMatrix)
+					} else {
+						in.show(ts, state.ctm, item)
+					}
+				}
+			}
+		case "Do":
+			if len(operands) == 1 {
+				xobjectName, _ := operands[0].(name)
+				err = in.form(resources, xobjectName, state.ctm, depth)
+				if err != nil {
+					return err
+				}
+			}
+		case "BI":
+			skipInlineImage(p)
+		}
+		operands = operands[:0]
+	}
+	return nil
+}
+
+func (ts *textState) nextLine() {
+	ts.lineMatrix = translation(0, -ts.leading).multiply(ts.lineMatrix)
+	ts.textMatrix = ts.lineMatrix
+}
+
+// show places the glyphs of a string and advances the text matrix (section 9.4.4).
+func (in *interpreter) show(ts *textState, ctm matrix, operand object) {
+	s, ok := operand.([]byte)
+	if !ok || ts.font == nil {
+		return
+	}
+	for _, code := range ts.font.codes(s) {
+		rendering := matrix{ts.size * ts.scale, 0, 0, ts.size, 0, ts.rise}.
+			multiply(ts.textMatrix).
+			multiply(ctm)
+		width := ts.font.width(code) / 1000
+		in.glyphs = append(in.glyphs, glyph{
+			x:     rendering[4],
+			y:     rendering[5],
+			width: width * rendering[0],
+			size:  math.Hypot(rendering[2], rendering[3]),
+			text:  ts.font.text(code),
+		})
+
+		advance := width*ts.size + ts.charSpace
+		// Word spacing applies to the single-byte code 32 only
+		if code == ' ' && !ts.font.twoByte {
+			advance += ts.wordSpace
+		}
+		ts.textMatrix = translation(advance*ts.scale, 0).multiply(ts.textMatrix)
+	}
+}
+
+// form draws a form XObject (section 8.10). Images and other XObjects are ignored.
+func (in *interpreter) form(resources dict, xobjectName name, ctm matrix, depth int) error {
+	if depth >= maxFormDepth {
+		return fmt.Errorf("forms nested too deeply")
+	}
+	xobjects, err := in.reader.resolveDict(resources["XObject"])
+	if err != nil {
+		return fmt.Errorf("reading xobjects: %w", err)
+	}
+	value, err := in.reader.resolve(xobjects[xobjectName])
+	if err != nil {
+		return fmt.Errorf("reading xobject %s: %w", xobjectName, err)
+	}
+	s, ok := value.(*stream)
+	if !ok || s.dict["Subtype"] != name("Form") {
+		return nil
+	}
+
+	data, err := in.reader.decode(s)
+	if err != nil {
+		return fmt.Errorf("decoding form %s: %w", xobjectName, err)
+	}
+	// Forms without resources use those of the page (deprecated, but common)
+	if own, ok := s.dict["Resources"]; ok {
+		resources, err = in.reader.resolveDict(own)
+		if err != nil {
+			return fmt.Errorf("reading form resources: %w", err)
+		}
+	}
+	if m, ok := s.dict["Matrix"].(array); ok {
+		if formMatrix, ok := matrixOperand(m); ok {
+			ctm = formMatrix.multiply(ctm)
+		}
+	}
+	return in.run(data, resources, ctm, depth+1)
+}
+
+// skipInlineImage skips the data of an inline image, up to and including EI.
+// The data is binary, so we look for EI surrounded by whitespace.
+func skipInlineImage(p *parser) {
+	start := bytes.Index(p.data[p.pos:], []byte("ID"))
+	if start == -1 {
+		p.pos = len(p.data)
+		return
+	}
+	for i := p.pos + start + 2; i+2 <= len(p.data); i++ {
+		if p.data[i] == 'E' && p.data[i+1] == 'I' && isWhitespace(p.data[i-1]) &&
+			(i+2 == len(p.data) || isWhitespace(p.data[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.data)
+}
+
+func numberOperand(operands []object, i int) float64 {
+	if i >= len(operands) {
+		return 0
+	}
+	value, _ := number(operands[i])
+	return value
+}
+
+func matrixOperand(operands []object) (matrix, bool) {
+	var m matrix
+	if len(operands) != 6 {
+		return m, false
+	}
+	for i := range m {
+		value, ok := number(operands[i])
+		if !ok {
+			return m, false
+		}
+		m[i] = value
+	}
+	return m, true
+}
@@ -0,0 +1,232 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Quest serves transcripts encrypted with an empty user password,
+// which only restricts what viewers let users do with them.
+// We implement the standard security handler (ISO 32000-1, section 7.6.3)
+// up to revision 4, which covers RC4 and AES-128; AES-256 is not supported.
+
+var errPasswordProtected = errors.New("PDF is password protected")
+
+// passwordPadding is used to pad passwords to 32 bytes (Algorithm 2, step a).
+var passwordPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+type cryptMethod int
+
+const (
+	cryptIdentity cryptMethod = iota
+	cryptRC4
+	cryptAES
+)
+
+type decrypter struct {
+	key []byte
+	// Streams and strings may be encrypted with different methods.
+	streamMethod cryptMethod
+	stringMethod cryptMethod
+}
+
+func newDecrypter(encrypt dict, id []byte) (*decrypter, error) {
+	if encrypt["Filter"] != name("Standard") {
+		return nil, fmt.Errorf("unsupported security handler: %v", encrypt["Filter"])
+	}
+	v, _ := encrypt["V"].(int)
+	revision, _ := encrypt["R"].(int)
+	owner, _ := encrypt["O"].([]byte)
+	user, _ := encrypt["U"].([]byte)
+	permissions, _ := encrypt["P"].(int)
+	if v > 4 || revision > 4 {
+		return nil, fmt.Errorf("unsupported encryption version %d revision %d", v, revision)
+	}
+	if len(owner) < 32 || len(user) < 32 {
+		return nil, fmt.Errorf("malformed encryption dictionary")
+	}
+
+	keyLength := 5
+	if bits, ok := encrypt["Length"].(int); ok && revision >= 3 {
+		keyLength = bits / 8
+	}
+	if keyLength < 5 || keyLength > 16 {
+		return nil, fmt.Errorf("invalid key length %d", keyLength)
+	}
+
+	d := &decrypter{streamMethod: cryptRC4, stringMethod: cryptRC4}
+	if v == 4 {
+		filters, _ := encrypt["CF"].(dict)
+		var err error
+		d.streamMethod, err = filterMethod(filters, encrypt["StmF"])
+		if err != nil {
+			return nil, err
+		}
+		d.stringMethod, err = filterMethod(filters, encrypt["StrF"])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Algorithm 2: computing an encryption key with the (empty) user password
+	hash := md5.New()
+	hash.Write(passwordPadding)
+	hash.Write(owner[:32])
+	binary.Write(hash, binary.LittleEndian, int32(permissions))
+	hash.Write(id)
+	if revision >= 4 && encrypt["EncryptMetadata"] == false {
+		hash.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := hash.Sum(nil)[:keyLength]
+	if revision >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key)
+			key = sum[:keyLength]
+		}
+	}
+	d.key = key
+
+	if !d.checkUserPassword(user, revision, id) {
+		return nil, errPasswordProtected
+	}
+	return d, nil
+}
+
+func filterMethod(filters dict, filterName object) (cryptMethod, error) {
+	if filterName == nil || filterName == name("Identity") {
+		return cryptIdentity, nil
+	}
+	filterNameValue, _ := filterName.(name)
+	filter, ok := filters[filterNameValue].(dict)
+	if !ok {
+		return 0, fmt.Errorf("crypt filter %v not found", filterName)
+	}
+	switch filter["CFM"] {
+	case nil, name("None"):
+		return cryptIdentity, nil
+	case name("V2"):
+		return cryptRC4, nil
+	case name("AESV2"):
+		return cryptAES, nil
+	}
+	return 0, fmt.Errorf("unsupported crypt filter method %v", filter["CFM"])
+}
+
+// checkUserPassword verifies that the empty password opens the document
+// with Algorithms 4 and 5, so we can fail early instead of reading garbage.
+func (d *decrypter) checkUserPassword(user []byte, revision int, id []byte) bool {
+	if revision < 3 {
+		expected := rc4Crypt(d.key, passwordPadding)
+		return bytes.Equal(expected, user[:32])
+	}
+
+	hash := md5.New()
+	hash.Write(passwordPadding)
+	hash.Write(id)
+	expected := hash.Sum(nil)
+	for i := 0; i < 20; i++ {
+		key := make([]byte, len(d.key))
+		for j := range key {
+			key[j] = d.key[j] ^ byte(i)
+		}
+		expected = rc4Crypt(key, expected)
+	}
+	return bytes.Equal(expected, user[:16])
+}
+
+// objectKey derives the key for a single object (Algorithm 1).
+func (d *decrypter) objectKey(num, gen int, method cryptMethod) []byte {
+	hash := md5.New()
+	hash.Write(d.key)
+	hash.Write([]byte{byte(num), byte(num >> 8), byte(num >> 16), byte(gen), byte(gen >> 8)})
+	if method == cryptAES {
+		hash.Write([]byte("sAlT"))
+	}
+	key := hash.Sum(nil)
+	if len(d.key)+5 < len(key) {
+		key = key[:len(d.key)+5]
+	}
+	return key
+}
+
+func (d *decrypter) decrypt(num, gen int, method cryptMethod, data []byte) ([]byte, error) {
+	switch method {
+	case cryptRC4:
+		return rc4Crypt(d.objectKey(num, gen, method), data), nil
+	case cryptAES:
+		return aesDecrypt(d.objectKey(num, gen, method), data)
+	}
+	return data, nil
+}
+
+func (d *decrypter) decryptStream(num, gen int, data []byte) ([]byte, error) {
+	return d.decrypt(num, gen, d.streamMethod, data)
+}
+
+// decryptStrings decrypts all strings within an object.
+func (d *decrypter) decryptStrings(num, gen int, o object) (object, error) {
+	switch v := o.(type) {
+	case []byte:
+		return d.decrypt(num, gen, d.stringMethod, v)
+	case array:
+		for i := range v {
+			value, err := d.decryptStrings(num, gen, v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = value
+		}
+	case dict:
+		for key := range v {
+			value, err := d.decryptStrings(num, gen, v[key])
+			if err != nil {
+				return nil, err
+			}
+			v[key] = value
+		}
+	}
+	return o, nil
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		// This only happens for keys outside of [1, 256] bytes, which we never derive
+		panic(err)
+	}
+	result := make([]byte, len(data))
+	c.XORKeyStream(result, data)
+	return result
+}
+
+// aesDecrypt decrypts AES-128 in CBC mode, with the IV in the first block.
+func aesDecrypt(key, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid AES ciphertext length %d", len(data))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(result, data[aes.BlockSize:])
+
+	// PKCS#5 padding is always present, but be lenient if it is invalid
+	padding := int(result[len(result)-1])
+	if 1 <= padding && padding <= aes.BlockSize {
+		return result[:len(result)-padding], nil
+	}
+	return result, nil
+}
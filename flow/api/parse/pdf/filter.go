@@ -0,0 +1,107 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"fmt"
+	"io"
+)
+
+// Decoded streams larger than this are most likely decompression bombs.
+// Transcript pages decode to tens of kilobytes.
+const maxStreamSize = 16 << 20
+
+// decode applies the filters of a stream to its data (ISO 32000-1, section 7.4).
+// Only filters that are used for text content are supported.
+func (r *reader) decode(s *stream) ([]byte, error) {
+	filters, err := r.resolve(s.dict["Filter"])
+	if err != nil {
+		return nil, err
+	}
+	var names array
+	switch v := filters.(type) {
+	case nil:
+	case name:
+		names = array{v}
+	case array:
+		names = v
+	default:
+		return nil, fmt.Errorf("invalid stream filter: %v", filters)
+	}
+
+	var params array
+	switch v := s.dict["DecodeParms"].(type) {
+	case dict:
+		params = array{v}
+	case array:
+		params = v
+	}
+
+	data := s.data
+	for i, filter := range names {
+		if i < len(params) {
+			if p, ok := params[i].(dict); ok {
+				if predictor, ok := p["Predictor"].(int); ok && predictor > 1 {
+					return nil, fmt.Errorf("unsupported predictor %d", predictor)
+				}
+			}
+		}
+
+		switch filter {
+		case name("FlateDecode"), name("Fl"):
+			data, err = inflate(data)
+		case name("ASCIIHexDecode"), name("AHx"):
+			data, err = asciiHexDecode(data)
+		case name("ASCII85Decode"), name("A85"):
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("applying %v: %w", filter, err)
+		}
+	}
+	return data, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result, err := io.ReadAll(io.LimitReader(reader, maxStreamSize+1))
+	if len(result) > maxStreamSize {
+		return nil, fmt.Errorf("stream exceeds %d bytes", maxStreamSize)
+	}
+	// Truncated streams and bad checksums are common, and poppler tolerates them
+	if err != nil && len(result) == 0 {
+		return nil, err
+	}
+	return result, nil
+}
+
+func asciiHexDecode(data []byte) ([]byte, error) {
+	// This is the same syntax as a hex string, but terminated by > alone
+	p := &parser{data: append(append([]byte{'<'}, data...), '>')}
+	if end := bytes.IndexByte(data, '>'); end != -1 {
+		p.data = p.data[:end+2]
+	}
+	return p.hexString()
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	if end := bytes.Index(data, []byte("~>")); end != -1 {
+		data = data[:end]
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	// Each z expands to four zero bytes, so this is the worst case
+	result := make([]byte, 4*len(data))
+	n, _, err := ascii85.Decode(result, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return result[:n], nil
+}
@@ -0,0 +1,391 @@
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Mappings larger than this in a ToUnicode CMap are surely malicious.
+const maxCMapEntries = 1 << 16
+
+// Glyph widths are in thousandths of text space units.
+// These are from the Adobe font metrics (AFM) of the standard 14 fonts.
+// Only the ASCII range is listed: other glyphs are rare enough that
+// an approximate width does not affect the layout.
+var (
+	helveticaWidths = [95]float64{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]float64{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// standardWidth returns the width of an ASCII character in one of the standard 14 fonts.
+func standardWidth(baseFont string, code int) float64 {
+	switch {
+	case strings.Contains(baseFont, "Courier"):
+		return 600
+	case strings.Contains(baseFont, "Helvetica") || strings.Contains(baseFont, "Arial"):
+		if code < 32 || code > 126 {
+			return 556
+		}
+		if strings.Contains(baseFont, "Bold") {
+			return helveticaBoldWidths[code-32]
+		}
+		return helveticaWidths[code-32]
+	}
+	// Times and Symbol are narrower on average
+	return 500
+}
+
+// winAnsiHigh maps the codes 0x80 to 0x9f of WinAnsiEncoding, which is
+// otherwise the same as Latin-1 (ISO 32000-1, Annex D).
+var winAnsiHigh = [32]rune{
+	0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+	0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+}
+
+type encoding [256]string
+
+var (
+	winAnsiEncoding  encoding
+	standardEncoding encoding
+)
+
+func init() {
+	for code := 32; code < 256; code++ {
+		r := rune(code)
+		if 0x80 <= code && code < 0xa0 {
+			r = winAnsiHigh[code-0x80]
+		}
+		if r != 0 && code != 0x7f {
+			winAnsiEncoding[code] = string(r)
+		}
+	}
+
+	// StandardEncoding differs from ASCII only in the quotes.
+	// Its upper half is approximated by Latin-1.
+	standardEncoding = winAnsiEncoding
+	standardEncoding['\''] = "’"
+	standardEncoding['`'] = "‘"
+}
+
+// glyphNames maps glyph names that appear in /Differences to text.
+// Single-character names and uniXXXX names are handled in glyphText.
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(", "parenright": ")",
+	"asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"colon": ":", "semicolon": ";", "less": "<", "equal": "=", "greater": ">",
+	"question": "?", "at": "@", "bracketleft": "[", "backslash": "\\", "bracketright": "]",
+	"asciicircum": "^", "underscore": "_", "grave": "`", "braceleft": "{", "bar": "|",
+	"braceright": "}", "asciitilde": "~",
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“", "quotedblright": "”",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…",
+	"minus": "−", "nbspace": " ", "fi": "fi", "fl": "fl",
+}
+
+func glyphText(glyph string) string {
+	if text, ok := glyphNames[glyph]; ok {
+		return text
+	}
+	if len(glyph) == 1 {
+		return glyph
+	}
+	if hex, ok := strings.CutPrefix(glyph, "uni"); ok && len(hex) == 4 {
+		if value, err := strconv.ParseUint(hex, 16, 16); err == nil {
+			return string(rune(value))
+		}
+	}
+	return ""
+}
+
+// font is what we need of a font to extract text: widths and the mapping to Unicode.
+type font struct {
+	// Composite (Type0) fonts have two-byte codes, simple fonts have one-byte codes.
+	twoByte bool
+	// Scale converts widths to thousandths of text space units (for Type3 fonts).
+	scale        float64
+	widths       map[int]float64
+	defaultWidth float64
+	baseFont     string
+	encoding     *encoding
+	toUnicode    map[int]string
+}
+
+func (f *font) codes(s []byte) []int {
+	var result []int
+	if f.twoByte {
+		for i := 0; i+1 < len(s); i += 2 {
+			result = append(result, int(s[i])<<8|int(s[i+1]))
+		}
+		return result
+	}
+	for _, c := range s {
+		result = append(result, int(c))
+	}
+	return result
+}
+
+func (f *font) width(code int) float64 {
+	if width, ok := f.widths[code]; ok {
+		return width * f.scale
+	}
+	if f.defaultWidth > 0 || f.twoByte {
+		return f.defaultWidth * f.scale
+	}
+	return standardWidth(f.baseFont, code)
+}
+
+func (f *font) text(code int) string {
+	if text, ok := f.toUnicode[code]; ok {
+		return text
+	}
+	if f.encoding != nil && code < len(f.encoding) {
+		return f.encoding[code]
+	}
+	return ""
+}
+
+func (r *reader) loadFont(o object) (*font, error) {
+	d, err := r.resolveDict(o)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFont, _ := d["BaseFont"].(name)
+	f := &font{
+		scale:    1,
+		widths:   make(map[int]float64),
+		baseFont: string(baseFont),
+	}
+
+	if d["Subtype"] == name("Type0") {
+		err = r.loadCompositeWidths(f, d)
+	} else {
+		err = r.loadSimpleWidths(f, d)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cmap, ok := d["ToUnicode"]; ok {
+		value, err := r.resolve(cmap)
+		if err != nil {
+			return nil, fmt.Errorf("reading ToUnicode: %w", err)
+		}
+		if s, ok := value.(*stream); ok {
+			data, err := r.decode(s)
+			if err != nil {
+				return nil, fmt.Errorf("decoding ToUnicode: %w", err)
+			}
+			f.toUnicode = parseCMap(data)
+		}
+	}
+	return f, nil
+}
+
+func (r *reader) loadSimpleWidths(f *font, d dict) error {
+	if d["Subtype"] == name("Type3") {
+		matrix, _ := r.resolveArray(d["FontMatrix"])
+		if len(matrix) > 0 {
+			if value, ok := number(matrix[0]); ok {
+				f.scale = value * 1000
+			}
+		}
+	}
+
+	first, _ := r.resolveNumber(d["FirstChar"])
+	widths, err := r.resolveArray(d["Widths"])
+	if err != nil {
+		return fmt.Errorf("reading widths: %w", err)
+	}
+	for i, width := range widths {
+		if value, ok := r.resolveNumber(width); ok {
+			f.widths[int(first)+i] = value
+		}
+	}
+	descriptor, err := r.resolveDict(d["FontDescriptor"])
+	if err != nil {
+		return fmt.Errorf("reading font descriptor: %w", err)
+	}
+	f.defaultWidth, _ = r.resolveNumber(descriptor["MissingWidth"])
+
+	f.encoding = &standardEncoding
+	encodingObject, err := r.resolve(d["Encoding"])
+	if err != nil {
+		return fmt.Errorf("reading encoding: %w", err)
+	}
+	differences := array{}
+	if encodingDict, ok := encodingObject.(dict); ok {
+		encodingObject = encodingDict["BaseEncoding"]
+		differences, _ = r.resolveArray(encodingDict["Differences"])
+	}
+	if encodingObject == name("WinAnsiEncoding") {
+		f.encoding = &winAnsiEncoding
+	}
+
+	if len(differences) > 0 {
+		custom := *f.encoding
+		code := 0
+		for _, item := range differences {
+			switch v := item.(type) {
+			case int:
+				code = v
+			case name:
+				if 0 <= code && code < len(custom) {
+					custom[code] = glyphText(string(v))
+				}
+				code++
+			}
+		}
+		f.encoding = &custom
+	}
+	return nil
+}
+
+// loadCompositeWidths reads the widths of the descendant CIDFont.
+// We assume the Identity-H encoding, which maps codes to CIDs one to one.
+func (r *reader) loadCompositeWidths(f *font, d dict) error {
+	f.twoByte = true
+	f.defaultWidth = 1000
+
+	descendants, err := r.resolveArray(d["DescendantFonts"])
+	if err != nil || len(descendants) == 0 {
+		return fmt.Errorf("reading descendant font: %v", err)
+	}
+	descendant, err := r.resolveDict(descendants[0])
+	if err != nil {
+		return fmt.Errorf("reading descendant font: %w", err)
+	}
+	if value, ok := r.resolveNumber(descendant["DW"]); ok {
+		f.defaultWidth = value
+	}
+
+	// W is a sequence of either "c [w1 w2 ...]" or "cfirst clast w" (section 9.7.4.3)
+	widths, err := r.resolveArray(descendant["W"])
+	if err != nil {
+		return fmt.Errorf("reading widths: %w", err)
+	}
+	for i := 0; i+1 < len(widths); {
+		first, ok := r.resolveNumber(widths[i])
+		if !ok {
+			return fmt.Errorf("malformed widths")
+		}
+		if list, err := r.resolveArray(widths[i+1]); err == nil && list != nil {
+			for j, width := range list {
+				if value, ok := r.resolveNumber(width); ok {
+					f.widths[int(first)+j] = value
+				}
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(widths) {
+			return fmt.Errorf("malformed widths")
+		}
+		last, _ := r.resolveNumber(widths[i+1])
+		width, _ := r.resolveNumber(widths[i+2])
+		if last-first > maxCMapEntries {
+			return fmt.Errorf("width range too large")
+		}
+		for code := int(first); code <= int(last); code++ {
+			f.widths[code] = width
+		}
+		i += 3
+	}
+	return nil
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap (section 9.10.3).
+// Errors are not fatal: we keep whatever mappings were read before them.
+func parseCMap(data []byte) map[int]string {
+	result := make(map[int]string)
+	p := &parser{data: data}
+	var operands []object
+
+	for !p.atEnd() && len(result) < maxCMapEntries {
+		value, err := p.object()
+		if err != nil {
+			break
+		}
+		word, ok := value.(keyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+
+		switch word {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				code, okCode := operands[i].([]byte)
+				text, okText := operands[i+1].([]byte)
+				if okCode && okText {
+					result[codeValue(code)] = utf16Text(text)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, okLow := operands[i].([]byte)
+				high, okHigh := operands[i+1].([]byte)
+				if !okLow || !okHigh {
+					continue
+				}
+				first, last := codeValue(low), codeValue(high)
+				if last-first > maxCMapEntries {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case []byte:
+					// Consecutive codes map to consecutive values of the last byte
+					text := append([]byte(nil), dst...)
+					for code := first; code <= last && len(text) > 0; code++ {
+						result[code] = utf16Text(text)
+						text[len(text)-1]++
+					}
+				case array:
+					for j, item := range dst {
+						if text, ok := item.([]byte); ok && first+j <= last {
+							result[first+j] = utf16Text(text)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return result
+}
+
+func codeValue(code []byte) int {
+	value := 0
+	for _, c := range code {
+		value = value<<8 | int(c)
+	}
+	return value
+}
+
+func utf16Text(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,34 @@
+package pdf
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzToText seeds the corpus with all transcripts we have and a minimal PDF.
+// Poppler crashing on malformed input is what the sandboxed worker is for,
+// so the target is most useful with -tags purego, where it should never panic.
+func FuzzToText(f *testing.F) {
+	paths, err := filepath.Glob("../transcript/testdata/*.pdf")
+	if err != nil {
+		f.Fatalf("listing testdata: %v", err)
+	}
+	paths = append(paths, "../../../../regtest/fixtures/transcript.pdf")
+	for _, path := range paths {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatalf("reading pdf: %v", err)
+		}
+		f.Add(bytes)
+	}
+	f.Add(buildPdf("BT /F1 8 Tf 1 0 0 1 50 700 Tm (CS)Tj 0 -10 Td [(Kern)-20(ed)]TJ ET", false))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		text, err := ToText(data)
+		if err == nil && !utf8.ValidString(text) {
+			t.Errorf("Expected valid UTF-8, but got %q", text)
+		}
+	})
+}
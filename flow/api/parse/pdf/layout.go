@@ -0,0 +1,111 @@
+package pdf
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// These thresholds are fractions of the font size.
+const (
+	// Glyphs on baselines closer than this are on the same line.
+	lineTolerance = 0.3
+	// Gaps between glyphs narrower than this are kerning, not spaces.
+	minWordGap = 0.1
+	// Gaps wider than this separate columns rather than words.
+	// A space is about a quarter of the font size in most fonts.
+	minColumnGap = 0.6
+	// Identical glyphs closer than this are drawn twice to look bold.
+	duplicateTolerance = 0.2
+)
+
+// Text far off to the right is not worth padding to its exact position.
+// Letter-sized pages fit about 150 columns.
+const maxColumn = 1000
+
+// layout arranges the glyphs of a page into lines of text, like poppler's
+// physical layout mode: text keeps its horizontal position on the page,
+// so columns of tables are separated by runs of spaces.
+// The transcript parser relies on those runs to tell columns from words.
+func layout(glyphs []glyph) string {
+	if len(glyphs) == 0 {
+		return ""
+	}
+
+	// Columns are measured in units of the average glyph width on the page
+	left := math.Inf(1)
+	var totalWidth float64
+	var count int
+	for _, g := range glyphs {
+		left = math.Min(left, g.x)
+		if strings.TrimSpace(g.text) != "" && g.width > 0 {
+			totalWidth += g.width
+			count++
+		}
+	}
+	columnWidth := 1.0
+	if count > 0 {
+		columnWidth = totalWidth / float64(count)
+	}
+
+	// Lines go from the top of the page down, which is decreasing y
+	sorted := make([]glyph, len(glyphs))
+	copy(sorted, glyphs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].y > sorted[j].y })
+
+	var result strings.Builder
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[start].y-sorted[end].y <= lineTolerance*sorted[start].size {
+			end++
+		}
+		result.WriteString(layoutLine(sorted[start:end], left, columnWidth))
+		result.WriteByte('\n')
+		start = end
+	}
+	return result.String()
+}
+
+func layoutLine(line []glyph, left, columnWidth float64) string {
+	sort.SliceStable(line, func(i, j int) bool { return line[i].x < line[j].x })
+
+	var text []rune
+	var previous *glyph
+	for i := range line {
+		g := &line[i]
+		if g.text == "" {
+			continue
+		}
+		if previous != nil && g.text == previous.text &&
+			math.Abs(g.x-previous.x) < duplicateTolerance*g.size {
+			continue
+		}
+
+		column := maxColumn
+		if position := (g.x - left) / columnWidth; position < maxColumn {
+			column = int(math.Round(position))
+		}
+		if previous == nil {
+			text = appendSpaces(text, column-len(text))
+		} else {
+			gap := g.x - (previous.x + previous.width)
+			switch {
+			case gap < minWordGap*g.size:
+			case gap < minColumnGap*g.size:
+				text = appendSpaces(text, 1)
+			default:
+				text = appendSpaces(text, max(column-len(text), 2))
+			}
+		}
+		text = append(text, []rune(g.text)...)
+		previous = g
+	}
+	return strings.TrimRight(string(text), " ")
+}
+
+func appendSpaces(text []rune, n int) []rune {
+	for i := 0; i < n; i++ {
+		text = append(text, ' ')
+	}
+	return text
+}
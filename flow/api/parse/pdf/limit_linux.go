@@ -0,0 +1,15 @@
+package pdf
+
+import "syscall"
+
+// limitResources limits the data memory (in bytes) and processor time (in seconds)
+// of this process. Exceeding the former makes allocations fail,
+// while exceeding the latter makes the kernel kill the process.
+func limitResources(memory uint64, cpuSeconds uint64) error {
+	// Unlike RLIMIT_AS, RLIMIT_DATA ignores the address space that Go reserves up front
+	err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: memory, Max: memory})
+	if err != nil {
+		return err
+	}
+	return syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: cpuSeconds, Max: cpuSeconds})
+}
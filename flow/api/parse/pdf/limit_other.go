@@ -0,0 +1,9 @@
+//go:build !linux
+
+package pdf
+
+// limitResources is not implemented outside of Linux, where we deploy.
+// There, workers are still subject to the timeout of ToTextSandboxed.
+func limitResources(memory uint64, cpuSeconds uint64) error {
+	return nil
+}
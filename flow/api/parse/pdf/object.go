@@ -0,0 +1,379 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// These are the PDF object types (ISO 32000-1, section 7.3).
+// Booleans, numbers and null are represented by bool, int, float64 and nil,
+// strings are []byte, as they are binary and may be in any encoding.
+type (
+	object interface{}
+	name   string
+	array  []object
+	dict   map[name]object
+	ref    struct{ num, gen int }
+	// Keywords are bare words: operators in content streams, obj, stream and the like.
+	keyword string
+)
+
+type stream struct {
+	dict dict
+	// Data is decrypted, but not yet decoded.
+	data []byte
+}
+
+// Nesting of arrays and dictionaries deeper than this is surely malicious.
+const maxNesting = 64
+
+var errEOF = errors.New("unexpected end of data")
+
+func isWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isRegular(c byte) bool {
+	return !isWhitespace(c) && !isDelimiter(c)
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// parser reads objects from the syntax of PDF files and content streams.
+type parser struct {
+	data []byte
+	pos  int
+	// References are only meaningful in files, not content streams,
+	// where looking for them would be a waste of time.
+	allowRefs bool
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\r' && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if isWhitespace(c) {
+			p.pos++
+		} else {
+			return
+		}
+	}
+}
+
+func (p *parser) atEnd() bool {
+	p.skipSpace()
+	return p.pos >= len(p.data)
+}
+
+// object reads the next object, or keyword, from the data.
+func (p *parser) object() (object, error) {
+	return p.nested(0)
+}
+
+func (p *parser) nested(depth int) (object, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("objects nested too deeply")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errEOF
+	}
+
+	c := p.data[p.pos]
+	switch {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literalString()
+	case c == '<' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '<':
+		return p.dict(depth)
+	case c == '<':
+		return p.hexString()
+	case c == '[':
+		return p.array(depth)
+	case c == '+' || c == '-' || c == '.' || ('0' <= c && c <= '9'):
+		return p.number()
+	case isDelimiter(c):
+		p.pos++
+		return keyword(c), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return keyword(word), nil
+}
+
+func (p *parser) name() name {
+	p.pos++ // skip /
+	var result []byte
+	for p.pos < len(p.data) && isRegular(p.data[p.pos]) {
+		c := p.data[p.pos]
+		p.pos++
+		// Since PDF 1.2, #xx stands for the byte with hex code xx
+		if c == '#' && p.pos+1 < len(p.data) {
+			hi, okHi := unhex(p.data[p.pos])
+			lo, okLo := unhex(p.data[p.pos+1])
+			if okHi && okLo {
+				c = hi<<4 | lo
+				p.pos += 2
+			}
+		}
+		result = append(result, c)
+	}
+	return name(result)
+}
+
+func (p *parser) literalString() ([]byte, error) {
+	p.pos++ // skip (
+	var result []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return result, nil
+			}
+		case '\r':
+			// Unescaped end-of-line markers of any kind are read as \n
+			if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+				p.pos++
+			}
+			c = '\n'
+		case '\\':
+			if p.pos >= len(p.data) {
+				return nil, errEOF
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A backslash at the end of a line continues the string
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if '0' <= c && c <= '7' {
+					value := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.data); i++ {
+						d := p.data[p.pos]
+						if d < '0' || '7' < d {
+							break
+						}
+						value = value*8 + int(d-'0')
+						p.pos++
+					}
+					c = byte(value)
+				}
+				// Otherwise, the backslash is ignored: this covers \\, \( and \)
+			}
+		}
+		result = append(result, c)
+	}
+	return nil, errEOF
+}
+
+func (p *parser) hexString() ([]byte, error) {
+	p.pos++ // skip <
+	var result []byte
+	var high byte
+	odd := false
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		if c == '>' {
+			// A missing final digit is assumed to be 0
+			if odd {
+				result = append(result, high<<4)
+			}
+			return result, nil
+		}
+		if isWhitespace(c) {
+			continue
+		}
+		digit, ok := unhex(c)
+		if !ok {
+			return nil, fmt.Errorf("invalid hex string character %q", c)
+		}
+		if odd {
+			result = append(result, high<<4|digit)
+		} else {
+			high = digit
+		}
+		odd = !odd
+	}
+	return nil, errEOF
+}
+
+func (p *parser) number() (object, error) {
+	start := p.pos
+	if c := p.data[p.pos]; c == '+' || c == '-' {
+		p.pos++
+	}
+	real := false
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '.' {
+			real = true
+		} else if c < '0' || '9' < c {
+			break
+		}
+		p.pos++
+	}
+	text := string(p.data[start:p.pos])
+
+	if !real {
+		value, err := strconv.Atoi(text)
+		if err == nil {
+			if p.allowRefs && value >= 0 {
+				if r, ok := p.tryRef(value); ok {
+					return r, nil
+				}
+			}
+			return value, nil
+		}
+		// Integers that overflow are still valid reals
+	}
+	// Some writers produce numbers like "-" or "1.2.3": read those as zero
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0.0, nil
+	}
+	return value, nil
+}
+
+// tryRef looks ahead for the "gen R" that would make num into a reference.
+func (p *parser) tryRef(num int) (ref, bool) {
+	saved := p.pos
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > start {
+		gen, err := strconv.Atoi(string(p.data[start:p.pos]))
+		p.skipSpace()
+		if err == nil && p.pos < len(p.data) && p.data[p.pos] == 'R' &&
+			(p.pos+1 == len(p.data) || !isRegular(p.data[p.pos+1])) {
+			p.pos++
+			return ref{num, gen}, true
+		}
+	}
+	p.pos = saved
+	return ref{}, false
+}
+
+func (p *parser) array(depth int) (array, error) {
+	p.pos++ // skip [
+	result := array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, errEOF
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return result, nil
+		}
+		value, err := p.nested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+}
+
+func (p *parser) dict(depth int) (dict, error) {
+	p.pos += 2 // skip <<
+	result := dict{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, errEOF
+		}
+		if p.data[p.pos] == '>' {
+			if p.pos+1 < len(p.data) && p.data[p.pos+1] == '>' {
+				p.pos += 2
+				return result, nil
+			}
+			return nil, fmt.Errorf("unbalanced dictionary")
+		}
+		key, err := p.nested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(name)
+		if !ok {
+			return nil, fmt.Errorf("dictionary key is not a name: %v", key)
+		}
+		value, err := p.nested(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		// A null value is equivalent to the key being absent
+		if value != nil {
+			result[k] = value
+		}
+	}
+}
+
+// number converts an integer or real object to float64.
+func number(o object) (float64, bool) {
+	switch v := o.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -1,3 +1,5 @@
+//go:build !purego
+
 #include <cstring>
 #include <string>
 #include <memory>
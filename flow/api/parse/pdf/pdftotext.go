@@ -1,3 +1,5 @@
+//go:build !purego
+
 package pdf
 
 // #cgo CFLAGS: -O2 -Wall -I/usr/include/poppler/cpp
@@ -11,7 +13,15 @@ import (
 	"unsafe"
 )
 
+// ToText converts a PDF to text with poppler.
+// Build with -tags purego to use PureToText instead, which does not need cgo.
 func ToText(data []byte) (string, error) {
+	return PopplerToText(data)
+}
+
+// PopplerToText converts a PDF to text with libpoppler-cpp,
+// laying out the text of each page as it would be physically printed.
+func PopplerToText(data []byte) (string, error) {
 	// Is this safe? Kind of: `data`, a []byte is a continguous array in Go,
 	// so we can safely point a C-land (const char*) to it,
 	// *provided* that C code does not attempt to find the end of the string,
@@ -0,0 +1,43 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PureToText converts a PDF to text without cgo.
+// It supports what Quest transcripts need: classic cross-reference tables,
+// the standard security handler, Flate-compressed content and simple fonts.
+// The layout of the text approximates that of PopplerToText,
+// closely enough for the transcript parser to read both the same way.
+func PureToText(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("empty PDF data")
+	}
+
+	r, err := newReader(data)
+	if err != nil {
+		return "", fmt.Errorf("malformed PDF: %w", err)
+	}
+	pages, err := r.pages()
+	if err != nil {
+		return "", fmt.Errorf("malformed PDF: %w", err)
+	}
+
+	var result strings.Builder
+	in := newInterpreter(r)
+	for i, page := range pages {
+		contents, err := in.pageContents(page.contents)
+		if err != nil {
+			return "", fmt.Errorf("reading page %d: %w", i+1, err)
+		}
+		in.glyphs = in.glyphs[:0]
+		err = in.run(contents, page.resources, identity, 0)
+		if err != nil {
+			return "", fmt.Errorf("reading page %d: %w", i+1, err)
+		}
+		result.WriteString(layout(in.glyphs))
+	}
+	return result.String(), nil
+}
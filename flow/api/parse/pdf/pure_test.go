@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+// buildPdf assembles a PDF with the given content stream on a single page.
+// If brokenXref is set, the cross-reference table points to wrong offsets.
+func buildPdf(content string, brokenXref bool) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content)+1, content),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	start := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		if brokenXref {
+			offset += 3
+		}
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, start)
+	return b.Bytes()
+}
+
+func TestPureToText(t *testing.T) {
+	content := `BT /F1 8 Tf
+1 0 0 1 50 700 Tm (CS)Tj
+1 0 0 1 100 700 Tm (341)Tj
+1 0 0 1 150 700 Tm (Algorithms)Tj
+1 0 0 1 50 690 Tm (Computer)Tj
+1 0 0 1 86.3 690 Tm (Science,)Tj
+0 -10 Td [(Kern)-20(ed) -400 (words \(and\) escapes)]TJ
+ET`
+	tests := []struct {
+		name       string
+		brokenXref bool
+	}{
+		{"valid", false},
+		{"broken xref", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PureToText(buildPdf(content, tt.brokenXref))
+			if err != nil {
+				t.Fatalf("converting: %v", err)
+			}
+			// Columns are separated by at least two spaces, words by exactly one
+			want := regexp.MustCompile("^CS {2,}341 {2,}Algorithms\nComputer Science,\n *Kerned words \\(and\\) escapes\n$")
+			if !want.MatchString(got) {
+				t.Errorf("Expected text to match %v, but got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestPureToTextMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a pdf", []byte("not a pdf")},
+		{"truncated", buildPdf("BT /F1 8 Tf (text)Tj ET", false)[:200]},
+		{"unterminated string", buildPdf("BT /F1 8 Tf (text", false)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PureToText(tt.data)
+			if err == nil {
+				t.Errorf("Expected error, but got none")
+			}
+		})
+	}
+}
+
+func TestLiteralString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`(plain)`, "plain"},
+		{`(nested (parens) ok)`, "nested (parens) ok"},
+		{`(\(\)\\\n\101\0537)`, "()\\\nA+7"},
+		{"(line \\\ncontinued)", "line continued"},
+		{"(crlf\r\nis lf)", "crlf\nis lf"},
+	}
+
+	for _, tt := range tests {
+		p := &parser{data: []byte(tt.input)}
+		got, err := p.object()
+		if err != nil {
+			t.Errorf("parsing %q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if string(got.([]byte)) != tt.want {
+			t.Errorf("parsing %q: expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build purego
+
+package pdf
+
+// ToText converts a PDF to text with PureToText.
+func ToText(data []byte) (string, error) {
+	return PureToText(data)
+}
@@ -0,0 +1,444 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Offsets and lengths are limited only by the size of the file,
+// but object graphs need explicit bounds to guard against cycles.
+const (
+	maxPages       = 256
+	maxXrefLinks   = 32
+	maxObjectDepth = 32
+)
+
+// objectHeaderRegexp finds object headers when the cross-reference table is broken.
+var objectHeaderRegexp = regexp.MustCompile(`(?m)(?:^|[\s])(\d+)\s+(\d+)\s+obj\b`)
+
+// reader provides random access to the objects of a PDF file.
+type reader struct {
+	data    []byte
+	offsets map[int]int
+	trailer dict
+	crypt   *decrypter
+	// Encryption dictionaries themselves are not encrypted.
+	encryptNum int
+	cache      map[int]object
+	// Objects currently being loaded, to detect reference cycles.
+	loading map[int]bool
+	// Whether offsets come from scanObjects rather than the xref table.
+	scanned bool
+}
+
+func newReader(data []byte) (*reader, error) {
+	r := &reader{
+		data:       data,
+		offsets:    make(map[int]int),
+		cache:      make(map[int]object),
+		loading:    make(map[int]bool),
+		encryptNum: -1,
+	}
+
+	err := r.readXref()
+	if err != nil {
+		// Many files in the wild have broken cross-reference tables,
+		// so do what other readers do and look for the objects ourselves.
+		r.scanObjects()
+		err = r.scanTrailer()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if encrypt, ok := r.trailer["Encrypt"]; ok {
+		if ref, ok := encrypt.(ref); ok {
+			r.encryptNum = ref.num
+		}
+		encryptDict, err := r.resolveDict(encrypt)
+		if err != nil {
+			return nil, fmt.Errorf("reading encryption dictionary: %w", err)
+		}
+		var id []byte
+		if ids, ok := r.trailer["ID"].(array); ok && len(ids) > 0 {
+			id, _ = ids[0].([]byte)
+		}
+		r.crypt, err = newDecrypter(encryptDict, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// readXref reads the chain of cross-reference tables that starts at startxref.
+// Cross-reference streams (PDF 1.5) are not supported: scanObjects handles those files.
+func (r *reader) readXref() error {
+	index := bytes.LastIndex(r.data, []byte("startxref"))
+	if index == -1 {
+		return fmt.Errorf("startxref not found")
+	}
+	p := &parser{data: r.data, pos: index + len("startxref")}
+	offset, err := p.object()
+	if err != nil {
+		return fmt.Errorf("reading startxref: %w", err)
+	}
+
+	visited := make(map[int]bool)
+	for i := 0; i < maxXrefLinks; i++ {
+		start, ok := offset.(int)
+		if !ok || start < 0 || start >= len(r.data) || visited[start] {
+			return fmt.Errorf("invalid xref offset: %v", offset)
+		}
+		visited[start] = true
+
+		trailer, err := r.readXrefSection(start)
+		if err != nil {
+			return err
+		}
+		// The first trailer is the most recent, so it takes precedence
+		if r.trailer == nil {
+			r.trailer = trailer
+		}
+		offset, ok = trailer["Prev"]
+		if !ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("too many xref sections")
+}
+
+func (r *reader) readXrefSection(start int) (dict, error) {
+	p := &parser{data: r.data, pos: start}
+	if word, err := p.object(); err != nil || word != keyword("xref") {
+		return nil, fmt.Errorf("xref table not found at %d", start)
+	}
+
+	for {
+		first, err := p.object()
+		if err != nil {
+			return nil, fmt.Errorf("reading xref subsection: %w", err)
+		}
+		if first == keyword("trailer") {
+			break
+		}
+		count, err := p.object()
+		if err != nil {
+			return nil, fmt.Errorf("reading xref subsection: %w", err)
+		}
+		num, okFirst := first.(int)
+		n, okCount := count.(int)
+		if !okFirst || !okCount || num < 0 || n < 0 {
+			return nil, fmt.Errorf("malformed xref subsection header")
+		}
+
+		for i := 0; i < n; i++ {
+			offset, err := p.object()
+			if err != nil {
+				return nil, fmt.Errorf("reading xref entry: %w", err)
+			}
+			if _, err = p.object(); err != nil {
+				return nil, fmt.Errorf("reading xref entry: %w", err)
+			}
+			kind, err := p.object()
+			if err != nil {
+				return nil, fmt.Errorf("reading xref entry: %w", err)
+			}
+			value, ok := offset.(int)
+			if !ok || value < 0 {
+				return nil, fmt.Errorf("malformed xref entry")
+			}
+			// Entries from later sections (read earlier) take precedence
+			if _, seen := r.offsets[num+i]; !seen && kind == keyword("n") {
+				r.offsets[num+i] = value
+			}
+		}
+	}
+
+	p.allowRefs = true
+	trailer, err := p.object()
+	if err != nil {
+		return nil, fmt.Errorf("reading trailer: %w", err)
+	}
+	trailerDict, ok := trailer.(dict)
+	if !ok {
+		return nil, fmt.Errorf("trailer is not a dictionary")
+	}
+	return trailerDict, nil
+}
+
+// scanObjects recovers the object offsets from the file contents.
+func (r *reader) scanObjects() {
+	r.scanned = true
+	r.offsets = make(map[int]int)
+	for _, match := range objectHeaderRegexp.FindAllSubmatchIndex(r.data, -1) {
+		num, err := strconv.Atoi(string(r.data[match[2]:match[3]]))
+		if err != nil {
+			continue
+		}
+		// Later definitions of an object are updates, so they take precedence
+		r.offsets[num] = match[2]
+	}
+}
+
+// scanTrailer recovers the trailer from the file contents.
+func (r *reader) scanTrailer() error {
+	index := bytes.LastIndex(r.data, []byte("trailer"))
+	if index == -1 {
+		return fmt.Errorf("trailer not found")
+	}
+	p := &parser{data: r.data, pos: index + len("trailer"), allowRefs: true}
+	trailer, err := p.object()
+	if err != nil {
+		return fmt.Errorf("reading trailer: %w", err)
+	}
+	trailerDict, ok := trailer.(dict)
+	if !ok {
+		return fmt.Errorf("trailer is not a dictionary")
+	}
+	r.trailer = trailerDict
+	return nil
+}
+
+// load reads the object with the given number, decrypting it if needed.
+// Missing objects are null, as the specification requires.
+func (r *reader) load(num int) (object, error) {
+	if value, ok := r.cache[num]; ok {
+		return value, nil
+	}
+	offset, ok := r.offsets[num]
+	if !ok {
+		return nil, nil
+	}
+	if r.loading[num] || len(r.loading) > maxObjectDepth {
+		return nil, fmt.Errorf("object %d refers to itself", num)
+	}
+	r.loading[num] = true
+	defer delete(r.loading, num)
+
+	p, gen, ok := r.objectHeader(num, offset)
+	if !ok && !r.scanned {
+		// The xref table has wrong offsets, which is also common
+		r.scanObjects()
+		offset, ok = r.offsets[num]
+		if !ok {
+			return nil, nil
+		}
+		p, gen, ok = r.objectHeader(num, offset)
+	}
+	if !ok {
+		return nil, fmt.Errorf("object %d not found at offset %d", num, offset)
+	}
+
+	value, err := p.object()
+	if err != nil {
+		return nil, fmt.Errorf("reading object %d: %w", num, err)
+	}
+	if r.crypt != nil && num != r.encryptNum {
+		value, err = r.crypt.decryptStrings(num, gen, value)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting object %d: %w", num, err)
+		}
+	}
+
+	if d, ok := value.(dict); ok {
+		saved := p.pos
+		if word, err := p.object(); err == nil && word == keyword("stream") {
+			data, err := r.streamData(p.pos, d)
+			if err != nil {
+				return nil, fmt.Errorf("reading object %d: %w", num, err)
+			}
+			if r.crypt != nil && num != r.encryptNum && d["Type"] != name("XRef") {
+				data, err = r.crypt.decryptStream(num, gen, data)
+				if err != nil {
+					return nil, fmt.Errorf("decrypting object %d: %w", num, err)
+				}
+			}
+			value = &stream{dict: d, data: data}
+		} else {
+			p.pos = saved
+		}
+	}
+
+	r.cache[num] = value
+	return value, nil
+}
+
+// objectHeader reads "num gen obj" at offset, and returns a parser positioned after it.
+func (r *reader) objectHeader(num, offset int) (*parser, int, bool) {
+	if offset < 0 || offset >= len(r.data) {
+		return nil, 0, false
+	}
+	p := &parser{data: r.data, pos: offset, allowRefs: true}
+	var header [3]object
+	for i := range header {
+		var err error
+		header[i], err = p.object()
+		if err != nil {
+			return nil, 0, false
+		}
+	}
+	gen, ok := header[1].(int)
+	if header[0] != num || !ok || header[2] != keyword("obj") {
+		return nil, 0, false
+	}
+	return p, gen, true
+}
+
+// streamData extracts the raw data of a stream that begins after the keyword at start.
+func (r *reader) streamData(start int, d dict) ([]byte, error) {
+	// The keyword is followed by CRLF or LF, but we also tolerate a lone CR
+	if start < len(r.data) && r.data[start] == '\r' {
+		start++
+	}
+	if start < len(r.data) && r.data[start] == '\n' {
+		start++
+	}
+
+	length, err := r.resolve(d["Length"])
+	if err != nil {
+		return nil, fmt.Errorf("reading stream length: %w", err)
+	}
+	if n, ok := length.(int); ok && n >= 0 && n <= len(r.data)-start {
+		rest := r.data[start+n:]
+		p := &parser{data: rest}
+		if word, err := p.object(); err == nil && word == keyword("endstream") {
+			return r.data[start : start+n], nil
+		}
+	}
+
+	// The length is wrong, so look for the end of the stream instead
+	end := bytes.Index(r.data[start:], []byte("endstream"))
+	if end == -1 {
+		return nil, fmt.Errorf("endstream not found")
+	}
+	data := r.data[start : start+end]
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return data, nil
+}
+
+// resolve follows references until it reaches a direct object.
+func (r *reader) resolve(o object) (object, error) {
+	for i := 0; i < maxObjectDepth; i++ {
+		ref, ok := o.(ref)
+		if !ok {
+			return o, nil
+		}
+		var err error
+		o, err = r.load(ref.num)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("reference chain too long")
+}
+
+// resolveDict resolves o and returns it as a dictionary, which is empty if o is null.
+// A stream is returned as its dictionary.
+func (r *reader) resolveDict(o object) (dict, error) {
+	value, err := r.resolve(o)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return dict{}, nil
+	case dict:
+		return v, nil
+	case *stream:
+		return v.dict, nil
+	}
+	return nil, fmt.Errorf("expected dictionary, got %T", value)
+}
+
+func (r *reader) resolveArray(o object) (array, error) {
+	value, err := r.resolve(o)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case array:
+		return v, nil
+	}
+	return nil, fmt.Errorf("expected array, got %T", value)
+}
+
+func (r *reader) resolveNumber(o object) (float64, bool) {
+	value, err := r.resolve(o)
+	if err != nil {
+		return 0, false
+	}
+	return number(value)
+}
+
+// page is a leaf of the page tree with inherited attributes filled in.
+type page struct {
+	resources dict
+	contents  object
+}
+
+// pages returns the pages of the document in order.
+func (r *reader) pages() ([]page, error) {
+	root, err := r.resolveDict(r.trailer["Root"])
+	if err != nil {
+		return nil, fmt.Errorf("reading catalog: %w", err)
+	}
+	if _, ok := root["Pages"]; !ok {
+		return nil, fmt.Errorf("catalog has no page tree")
+	}
+
+	var result []page
+	visited := make(map[ref]bool)
+	var walk func(node object, resources dict, depth int) error
+	walk = func(node object, resources dict, depth int) error {
+		if ref, ok := node.(ref); ok {
+			if visited[ref] {
+				return fmt.Errorf("page tree has a cycle")
+			}
+			visited[ref] = true
+		}
+		if depth > maxObjectDepth || len(result) >= maxPages {
+			return fmt.Errorf("too many pages")
+		}
+
+		d, err := r.resolveDict(node)
+		if err != nil {
+			return fmt.Errorf("reading page tree: %w", err)
+		}
+		// Resources are inheritable (section 7.7.3.4)
+		if own, ok := d["Resources"]; ok {
+			resources, err = r.resolveDict(own)
+			if err != nil {
+				return fmt.Errorf("reading resources: %w", err)
+			}
+		}
+
+		kids, ok := d["Kids"]
+		if d["Type"] == name("Page") || !ok {
+			result = append(result, page{resources: resources, contents: d["Contents"]})
+			return nil
+		}
+		kidsArray, err := r.resolveArray(kids)
+		if err != nil {
+			return fmt.Errorf("reading page tree: %w", err)
+		}
+		for _, kid := range kidsArray {
+			err = walk(kid, resources, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = walk(root["Pages"], dict{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
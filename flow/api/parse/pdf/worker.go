@@ -0,0 +1,157 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Limits on PDF conversion. Transcripts are a few pages long,
+// so they are well within these limits, which only stop malicious files.
+const (
+	// MaxSize is the largest PDF we accept, in bytes.
+	MaxSize = 4 << 20
+	// maxTextSize is the most text that a worker may output, in bytes.
+	maxTextSize = 4 << 20
+	// maxErrorSize is the most error output that we keep from a worker.
+	maxErrorSize = 4 << 10
+	// workerTimeout is the wall clock time that a worker may take.
+	workerTimeout = 5 * time.Second
+	// workerCpuSeconds is the processor time that a worker may take.
+	workerCpuSeconds = 5
+	// workerMemory is the most data memory that a worker may allocate.
+	workerMemory = 512 << 20
+	// maxWorkers is the most workers that may run at once.
+	maxWorkers = 4
+)
+
+// workerEnv is set in the environment of a worker process.
+const workerEnv = "FLOW_PDF_WORKER"
+
+// extraWorkerEnv is added to the environment of workers, which is otherwise empty.
+// Only tests set it, to control their workers.
+var extraWorkerEnv []string
+
+// The worker exits with this code if the PDF is malformed,
+// and with other codes if it crashes (e.g. runs out of memory).
+const exitConversionFailed = 3
+
+var (
+	ErrTooLarge = errors.New("PDF exceeds size limit")
+	ErrTimeout  = errors.New("PDF conversion timed out")
+	// ErrKilled means that the worker crashed or exceeded a resource limit.
+	ErrKilled = errors.New("PDF conversion killed")
+)
+
+var workerSlots = make(chan struct{}, maxWorkers)
+
+// IsWorker returns whether this process was started to convert a PDF.
+func IsWorker() bool {
+	return os.Getenv(workerEnv) != ""
+}
+
+// ServeWorker converts a PDF from stdin to text on stdout with ToText and exits.
+// It limits its own resources first, as they are inherited by anything it runs.
+func ServeWorker() {
+	err := limitResources(workerMemory, workerCpuSeconds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "limiting resources: %v", err)
+		os.Exit(exitConversionFailed)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(os.Stdin, MaxSize+1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading input: %v", err)
+		os.Exit(exitConversionFailed)
+	}
+	if len(data) > MaxSize {
+		fmt.Fprint(os.Stderr, ErrTooLarge)
+		os.Exit(exitConversionFailed)
+	}
+
+	text, err := ToText(data)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(exitConversionFailed)
+	}
+	os.Stdout.WriteString(text)
+	os.Exit(0)
+}
+
+// limitedBuffer is a buffer that refuses to grow past a limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("output exceeds %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
+
+// ToTextSandboxed converts a PDF to text with ToText in a worker process.
+// The worker is this executable, so main must call ServeWorker if IsWorker.
+// Unlike with ToText, a crash or hang while converting does not affect the caller.
+func ToTextSandboxed(ctx context.Context, data []byte) (string, error) {
+	if len(data) > MaxSize {
+		return "", ErrTooLarge
+	}
+
+	select {
+	case workerSlots <- struct{}{}:
+		defer func() { <-workerSlots }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("finding worker executable: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, workerTimeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxTextSize}
+	stderr := &limitedBuffer{limit: maxErrorSize}
+	cmd := exec.CommandContext(ctx, executable)
+	// The worker needs no configuration: time zones are embedded and it runs nothing,
+	// so none of our environment, such as database credentials, is passed on
+	cmd.Env = append([]string{workerEnv + "=1"}, extraWorkerEnv...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Do not wait for pipes to close if the worker left children behind
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", ErrTimeout
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == exitConversionFailed {
+			return "", errors.New(stderr.String())
+		}
+		// This is either a signal (exit code -1) or a runtime crash
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		return "", fmt.Errorf("%w: %v: %s", ErrKilled, exitErr, lines[0])
+	}
+	if err != nil {
+		// The worker could not start or its output exceeded the limit
+		return "", fmt.Errorf("%w: %v", ErrKilled, err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,84 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// workerModeEnv makes the test worker misbehave instead of converting.
+const workerModeEnv = "FLOW_PDF_WORKER_TEST_MODE"
+
+// The test binary serves as the worker for ToTextSandboxed.
+func TestMain(m *testing.M) {
+	if !IsWorker() {
+		os.Exit(m.Run())
+	}
+
+	switch os.Getenv(workerModeEnv) {
+	case "hang":
+		time.Sleep(time.Hour)
+	case "crash":
+		syscall.Kill(os.Getpid(), syscall.SIGSEGV)
+	case "allocate":
+		limitResources(workerMemory, workerCpuSeconds)
+		var chunks [][]byte
+		for i := 0; i < 2*workerMemory/(1<<20); i++ {
+			chunks = append(chunks, make([]byte, 1<<20))
+			chunks[i][0] = 1
+		}
+	}
+	ServeWorker()
+}
+
+func TestToTextSandboxed(t *testing.T) {
+	data := buildPdf("BT /F1 8 Tf 1 0 0 1 50 700 Tm (Sandboxed)Tj ET", false)
+	got, err := ToTextSandboxed(context.Background(), data)
+	if err != nil {
+		t.Fatalf("converting: %v", err)
+	}
+	if !strings.Contains(got, "Sandboxed") {
+		t.Errorf("Expected text to contain %q, but got %q", "Sandboxed", got)
+	}
+}
+
+func TestToTextSandboxedMalformed(t *testing.T) {
+	_, err := ToTextSandboxed(context.Background(), []byte("not a pdf"))
+	if err == nil || errors.Is(err, ErrKilled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected conversion error, but got %v", err)
+	}
+}
+
+func TestToTextSandboxedLimits(t *testing.T) {
+	data := buildPdf("BT /F1 8 Tf (text)Tj ET", false)
+	tests := []struct {
+		name    string
+		mode    string
+		data    []byte
+		timeout time.Duration
+		want    error
+	}{
+		{"too large", "", make([]byte, MaxSize+1), time.Minute, ErrTooLarge},
+		{"timeout", "hang", data, 500 * time.Millisecond, ErrTimeout},
+		{"crash", "crash", data, time.Minute, ErrKilled},
+		{"memory limit", "allocate", data, time.Minute, ErrKilled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extraWorkerEnv = []string{workerModeEnv + "=" + tt.mode}
+			defer func() { extraWorkerEnv = nil }()
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			_, err := ToTextSandboxed(ctx, tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, but got %v", tt.want, err)
+			}
+		})
+	}
+}
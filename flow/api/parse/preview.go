@@ -0,0 +1,271 @@
+package parse
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flow/api/parse/diagnostic"
+	"flow/api/parse/schedule"
+	"flow/api/parse/transcript"
+	"flow/api/serde"
+	"flow/common/db"
+)
+
+// isPreview reports whether the request asks for a preview (?preview=true)
+// of what an import would change, instead of the import itself.
+func isPreview(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("preview")
+	if value == "" {
+		return false, nil
+	}
+	preview, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, serde.WithStatus(http.StatusBadRequest, fmt.Errorf("parsing preview flag: %w", err))
+	}
+	return preview, nil
+}
+
+type courseTaken struct {
+	Code string `json:"code"`
+	// Zero for transfer credits, which belong to no term
+	TermId int `json:"term_id"`
+}
+
+type transcriptPreview struct {
+	ProgramName string        `json:"program_name"`
+	Added       []courseTaken `json:"added"`
+	Removed     []courseTaken `json:"removed"`
+	// Codes of courses on the transcript that are not in the database
+	UnknownCodes []string           `json:"unknown_codes"`
+	Diagnostics  *diagnostic.Report `json:"diagnostics"`
+}
+
+type scheduledSection struct {
+	ClassNumber int    `json:"class_number"`
+	CourseCode  string `json:"course_code"`
+	SectionName string `json:"section_name"`
+}
+
+type schedulePreview struct {
+	TermId  int                `json:"term_id"`
+	Added   []scheduledSection `json:"added"`
+	Removed []scheduledSection `json:"removed"`
+	// Class numbers on the schedule for which there is no section in the database
+	FailedClasses []int `json:"failed_classes"`
+}
+
+// schedulesPreview has the preview for each term on the schedule,
+// as well as their totals, like scheduleResponse.
+type schedulesPreview struct {
+	Added         []scheduledSection `json:"added"`
+	Removed       []scheduledSection `json:"removed"`
+	FailedClasses []int              `json:"failed_classes"`
+	Terms         []schedulePreview  `json:"terms"`
+	Diagnostics   *diagnostic.Report `json:"diagnostics"`
+}
+
+// diffKeys returns the elements of next that are not in prev and vice versa.
+// The results are in the order of next and prev respectively.
+func diffKeys[T comparable](prev, next []T) (added, removed []T) {
+	inPrev := make(map[T]bool, len(prev))
+	for _, key := range prev {
+		inPrev[key] = true
+	}
+	inNext := make(map[T]bool, len(next))
+	for _, key := range next {
+		inNext[key] = true
+		if !inPrev[key] {
+			added = append(added, key)
+		}
+	}
+	for _, key := range prev {
+		if !inNext[key] {
+			removed = append(removed, key)
+		}
+	}
+	return added, removed
+}
+
+// The same rows as are deleted by deleteTranscriptQuery and deleteTransferCreditsQuery
+const selectTranscriptQuery = `
+SELECT c.code, COALESCE(uct.term_id, 0)
+FROM user_course_taken uct
+  JOIN course c ON c.id = uct.course_id
+WHERE (uct.term_id <= $1 OR uct.term_id IS NULL) AND uct.user_id = $2
+ORDER BY uct.term_id NULLS FIRST, c.code
+`
+
+const selectKnownCodesQuery = `
+SELECT code FROM course WHERE code = ANY($1)
+`
+
+// transcriptCourses returns the courses that saveTranscript would insert,
+// transfer credits first, and the codes of those it would skip as unknown.
+func transcriptCourses(summary *transcript.Summary, known map[string]bool) ([]courseTaken, []string) {
+	var courses []courseTaken
+	var unknownCodes []string
+	add := func(code string, termId int) {
+		// Unknown courses would not be inserted by insertTranscriptQuery or insertTransferCreditQuery
+		if known[code] {
+			courses = append(courses, courseTaken{Code: code, TermId: termId})
+		} else {
+			unknownCodes = append(unknownCodes, code)
+		}
+	}
+	for _, course := range summary.TransferCredits {
+		add(course.Code, 0)
+	}
+	for _, termSummary := range summary.TermSummaries {
+		for _, course := range termSummary.Courses {
+			add(course.Code, termSummary.TermId)
+		}
+	}
+	return courses, unknownCodes
+}
+
+func previewTranscript(tx *db.Tx, summary *transcript.Summary, userId int) (*transcriptPreview, error) {
+	err := validateTranscript(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	for _, course := range summary.TransferCredits {
+		codes = append(codes, course.Code)
+	}
+	for _, termSummary := range summary.TermSummaries {
+		for _, course := range termSummary.Courses {
+			codes = append(codes, course.Code)
+		}
+	}
+
+	known := make(map[string]bool)
+	rows, err := tx.Query(selectKnownCodesQuery, codes)
+	if err != nil {
+		return nil, fmt.Errorf("querying course codes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		err = rows.Scan(&code)
+		if err != nil {
+			return nil, fmt.Errorf("reading course code: %w", err)
+		}
+		known[code] = true
+	}
+
+	preview := transcriptPreview{ProgramName: summary.ProgramName}
+	next, unknownCodes := transcriptCourses(summary, known)
+	preview.UnknownCodes = unknownCodes
+
+	rows, err = tx.Query(selectTranscriptQuery, maxTermId(summary), userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying courses taken: %w", err)
+	}
+	defer rows.Close()
+	var prev []courseTaken
+	for rows.Next() {
+		var course courseTaken
+		err = rows.Scan(&course.Code, &course.TermId)
+		if err != nil {
+			return nil, fmt.Errorf("reading course taken: %w", err)
+		}
+		prev = append(prev, course)
+	}
+
+	preview.Added, preview.Removed = diffKeys(prev, next)
+	return &preview, nil
+}
+
+// The same rows as are deleted by deleteScheduleQuery
+const selectScheduleQuery = `
+SELECT cs.class_number, c.code, cs.section_name
+FROM user_schedule us
+  JOIN course_section cs ON cs.id = us.section_id
+  JOIN course c ON c.id = cs.course_id
+WHERE us.user_id = $1 AND cs.term_id = $2
+ORDER BY cs.class_number
+`
+
+const selectSectionsQuery = `
+SELECT cs.class_number, c.code, cs.section_name
+FROM course_section cs
+  JOIN course c ON c.id = cs.course_id
+WHERE cs.term_id = $1 AND cs.class_number = ANY($2)
+`
+
+func scanSections(tx *db.Tx, query string, args ...interface{}) ([]scheduledSection, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying sections: %w", err)
+	}
+	defer rows.Close()
+
+	var sections []scheduledSection
+	for rows.Next() {
+		var section scheduledSection
+		err = rows.Scan(&section.ClassNumber, &section.CourseCode, &section.SectionName)
+		if err != nil {
+			return nil, fmt.Errorf("reading section: %w", err)
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func previewSchedule(tx *db.Tx, summary *schedule.Summary, userId int) (*schedulePreview, error) {
+	classNumbers := make([]int, len(summary.Classes))
+	for i, class := range summary.Classes {
+		classNumbers[i] = class.Number
+	}
+
+	found, err := scanSections(tx, selectSectionsQuery, summary.TermId, classNumbers)
+	if err != nil {
+		return nil, err
+	}
+	sectionByNumber := make(map[int]scheduledSection)
+	for _, section := range found {
+		sectionByNumber[section.ClassNumber] = section
+	}
+
+	preview := schedulePreview{TermId: summary.TermId}
+	var next []scheduledSection
+	for _, number := range classNumbers {
+		if section, ok := sectionByNumber[number]; ok {
+			next = append(next, section)
+		} else {
+			preview.FailedClasses = append(preview.FailedClasses, number)
+		}
+	}
+
+	prev, err := scanSections(tx, selectScheduleQuery, userId, summary.TermId)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Added, preview.Removed = diffKeys(prev, next)
+	return &preview, nil
+}
+
+func previewSchedules(tx *db.Tx, summaries []*schedule.Summary, userId int) (*schedulesPreview, error) {
+	for _, summary := range summaries {
+		err := validateSchedule(summary)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var preview schedulesPreview
+	for _, summary := range summaries {
+		termPreview, err := previewSchedule(tx, summary, userId)
+		if err != nil {
+			return nil, fmt.Errorf("term %d: %w", summary.TermId, err)
+		}
+		preview.Added = append(preview.Added, termPreview.Added...)
+		preview.Removed = append(preview.Removed, termPreview.Removed...)
+		preview.FailedClasses = append(preview.FailedClasses, termPreview.FailedClasses...)
+		preview.Terms = append(preview.Terms, *termPreview)
+	}
+	return &preview, nil
+}
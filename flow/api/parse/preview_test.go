@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"testing"
+
+	"flow/api/parse/transcript"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffKeys(t *testing.T) {
+	prev := []courseTaken{{"cs135", 1189}, {"math135", 1189}, {"cs136", 1191}}
+	next := []courseTaken{{"cs135", 1189}, {"math135", 1191}, {"cs136", 1191}, {"cs245", 1195}}
+
+	added, removed := diffKeys(prev, next)
+	wantAdded := []courseTaken{{"math135", 1191}, {"cs245", 1195}}
+	wantRemoved := []courseTaken{{"math135", 1189}}
+	if !cmp.Equal(wantAdded, added) {
+		t.Errorf("added mismatch (-want +got):\n%s", cmp.Diff(wantAdded, added))
+	}
+	if !cmp.Equal(wantRemoved, removed) {
+		t.Errorf("removed mismatch (-want +got):\n%s", cmp.Diff(wantRemoved, removed))
+	}
+}
+
+func TestTranscriptCourses(t *testing.T) {
+	summary := &transcript.Summary{
+		TermSummaries: []transcript.TermSummary{
+			{TermId: 1189, Courses: []transcript.Course{{Code: "cs135"}, {Code: "pd1"}}},
+			{TermId: 1191, Courses: []transcript.Course{{Code: "cs136"}}},
+		},
+		TransferCredits: []transcript.Course{{Code: "chem120"}, {Code: "chem1xx"}},
+	}
+	known := map[string]bool{"cs135": true, "cs136": true, "chem120": true}
+
+	courses, unknownCodes := transcriptCourses(summary, known)
+	wantCourses := []courseTaken{{"chem120", 0}, {"cs135", 1189}, {"cs136", 1191}}
+	wantUnknown := []string{"chem1xx", "pd1"}
+	if !cmp.Equal(wantCourses, courses) {
+		t.Errorf("courses mismatch (-want +got):\n%s", cmp.Diff(wantCourses, courses))
+	}
+	if !cmp.Equal(wantUnknown, unknownCodes) {
+		t.Errorf("unknown codes mismatch (-want +got):\n%s", cmp.Diff(wantUnknown, unknownCodes))
+	}
+
+	// A transfer credit that is no longer on the transcript is removed,
+	// even though no term of the transcript includes it
+	prev := []courseTaken{{"math135", 0}, {"cs135", 1189}}
+	added, removed := diffKeys(prev, courses)
+	wantAdded := []courseTaken{{"chem120", 0}, {"cs136", 1191}}
+	wantRemoved := []courseTaken{{"math135", 0}}
+	if !cmp.Equal(wantAdded, added) {
+		t.Errorf("added mismatch (-want +got):\n%s", cmp.Diff(wantAdded, added))
+	}
+	if !cmp.Equal(wantRemoved, removed) {
+		t.Errorf("removed mismatch (-want +got):\n%s", cmp.Diff(wantRemoved, removed))
+	}
+}
+
+func TestDiffKeysUnchanged(t *testing.T) {
+	sections := []scheduledSection{{4896, "cs145", "LEC 001"}, {4897, "cs145", "TUT 101"}}
+
+	added, removed := diffKeys(sections, sections)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no changes, but got added %v and removed %v", added, removed)
+	}
+}
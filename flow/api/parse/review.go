@@ -0,0 +1,37 @@
+package parse
+
+import (
+	"log"
+
+	"flow/api/parse/diagnostic"
+	"flow/common/db"
+)
+
+// Imports parsed with less confidence than this are worth a look,
+// as they likely come from a format that the parsers do not handle yet.
+const reviewConfidence = 0.9
+
+const insertReviewQuery = `
+INSERT INTO secret.parse_review(kind, input, confidence, unmatched_lines, mismatches)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+func needsReview(report *diagnostic.Report) bool {
+	return report.Confidence < reviewConfidence
+}
+
+// saveReview stores an import that the user consented to share, once anonymized.
+// This goes through conn rather than the transaction of the import,
+// as failed imports, which are the most interesting, are rolled back.
+// The import should go ahead regardless, so errors are only logged.
+func saveReview(conn *db.Conn, kind string, anonymized string, report *diagnostic.Report) {
+	// The arrays are NOT NULL, but nil slices are encoded as NULL
+	unmatchedLines := append([]string{}, report.UnmatchedLines...)
+	mismatches := append([]string{}, report.Mismatches...)
+	_, err := conn.Exec(insertReviewQuery, kind, anonymized, report.Confidence, unmatchedLines, mismatches)
+	if err != nil {
+		log.Printf("Saving %s for review failed: %v", kind, err)
+		return
+	}
+	log.Printf("Saved %s with confidence %.2f for review", kind, report.Confidence)
+}
@@ -0,0 +1,30 @@
+package schedule
+
+import "strings"
+
+// Anonymize removes the name of the student from a schedule.
+// Quest shows it among the navigation at the top of the page,
+// so we drop everything before the first line that mentions a term.
+// HTML is converted to text first, as its head is similarly personal.
+// iCalendar exports have no name and are returned unchanged.
+// If no term is mentioned, we cannot tell where the name is, so ok is false.
+func Anonymize(text string) (anonymized string, ok bool) {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+	switch {
+	case strings.HasPrefix(strings.ToUpper(trimmed), "BEGIN:VCALENDAR"):
+		return text, true
+	case strings.HasPrefix(trimmed, "<"):
+		converted, err := htmlToText(trimmed)
+		if err != nil {
+			return "", false
+		}
+		text = converted
+	}
+
+	loc := termRegexp.FindStringIndex(text)
+	if loc == nil {
+		return "", false
+	}
+	lineStart := strings.LastIndex(text[:loc[0]], "\n") + 1
+	return text[lineStart:], true
+}
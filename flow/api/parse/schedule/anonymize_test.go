@@ -0,0 +1,54 @@
+package schedule
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Fixtures have placeholders such as "Student 3" in place of names
+var studentRegexp = regexp.MustCompile(`Student \d`)
+
+func TestAnonymize(t *testing.T) {
+	paths, err := filepath.Glob("testdata/schedule-*")
+	if err != nil {
+		t.Fatalf("listing testdata: %v", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			bytes, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("opening testdata: %v", err)
+			}
+			text := string(bytes)
+
+			anonymized, ok := Anonymize(text)
+			if !ok {
+				t.Fatalf("Expected to find the start of the schedule")
+			}
+			if name := studentRegexp.FindString(anonymized); name != "" {
+				t.Errorf("Expected %q to be removed", name)
+			}
+
+			// Nothing of use to the parser should be removed
+			want, _, err := Parse(text)
+			if err != nil {
+				t.Fatalf("parsing original: %v", err)
+			}
+			got, _, err := Parse(anonymized)
+			if err != nil {
+				t.Fatalf("parsing anonymized: %v", err)
+			}
+			if !cmp.Equal(want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, got))
+			}
+		})
+	}
+
+	if _, ok := Anonymize("Student 3\nMy Academics\n"); ok {
+		t.Errorf("Expected a schedule without a term to be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"regexp"
+
+	"flow/api/parse/diagnostic"
+)
+
+// Lines with a time of day are almost always meeting times.
+var clockRegexp = regexp.MustCompile(`\d{1,2}:\d{2}\s*[AaPp][Mm]`)
+
+// diagnoseText reports meeting times that were not parsed
+// and classes that are missing details, which are all signs
+// that Quest changed the layout of the schedule.
+func diagnoseText(text string, summaries []*Summary, report *diagnostic.Report) {
+	var times int
+	for _, line := range trimLines(text) {
+		if !clockRegexp.MatchString(line) {
+			continue
+		}
+		if timesRegexp.MatchString(line) {
+			times++
+		} else {
+			report.Unmatched(line)
+		}
+	}
+
+	var parsedTimes int
+	for _, summary := range summaries {
+		for _, class := range summary.Classes {
+			report.Matched++
+			if len(class.Meetings) == 0 {
+				report.Mismatch("class %d has no meetings", class.Number)
+			}
+			if class.Section == "" || class.Component == "" {
+				report.Mismatch("class %d has no section name", class.Number)
+			}
+			for _, meeting := range class.Meetings {
+				if meeting.StartSeconds != nil {
+					parsedTimes++
+				}
+			}
+		}
+	}
+	if times != parsedTimes {
+		report.Mismatch("%d meeting times, but %d were parsed", times, parsedTimes)
+	}
+}
@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"flow/common/util"
+)
+
+// Fixtures used by the regression tests of the API
+const regtestFixtures = "../../../../regtest/fixtures"
+
+// FuzzParse seeds the corpus with all schedules we have, in every format.
+func FuzzParse(f *testing.F) {
+	paths, err := filepath.Glob("testdata/schedule-*")
+	if err != nil {
+		f.Fatalf("listing testdata: %v", err)
+	}
+	paths = append(
+		paths,
+		filepath.Join(regtestFixtures, "schedule.txt"),
+		filepath.Join(regtestFixtures, "schedule-2025"),
+	)
+	for _, path := range paths {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatalf("opening testdata: %v", err)
+		}
+		f.Add(string(bytes))
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		summaries, report, err := Parse(text)
+		if report == nil {
+			t.Fatalf("Expected a report")
+		}
+		if report.Confidence < 0 || report.Confidence > 1 {
+			t.Errorf("Expected confidence between 0 and 1, but got %v", report.Confidence)
+		}
+		if err != nil {
+			if report.Confidence != 0 {
+				t.Errorf("Expected no confidence in a failed parse, but got %v", report.Confidence)
+			}
+			return
+		}
+
+		if len(summaries) == 0 {
+			t.Fatalf("Expected at least one term")
+		}
+		seen := make(map[int]bool)
+		for _, summary := range summaries {
+			if !util.IsValidTermId(summary.TermId) {
+				t.Errorf("Invalid term id %d", summary.TermId)
+			}
+			// Spans of the same term are merged
+			if seen[summary.TermId] {
+				t.Errorf("Term %d appears more than once", summary.TermId)
+			}
+			seen[summary.TermId] = true
+			for _, class := range summary.Classes {
+				if class.Number <= 0 {
+					t.Errorf("Invalid class number %d in term %d", class.Number, summary.TermId)
+				}
+			}
+		}
+	})
+}
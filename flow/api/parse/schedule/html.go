@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Quest's "printable" schedule is a page of nested tables.
+// We turn it into text that looks like what users copy out of Quest,
+// with every table cell on its own line, and parse that instead.
+
+// Scripts and styles may contain unescaped markup, so we drop them before decoding.
+var scriptRegexp = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)\s*>`)
+
+// Elements that start a new line of text when opened or closed.
+var blockElements = map[string]bool{
+	"article": true, "blockquote": true, "br": true, "caption": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "option": true,
+	"p": true, "pre": true, "section": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "title": true,
+	"tr": true, "ul": true,
+}
+
+func htmlToText(html string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(scriptRegexp.ReplaceAllString(html, "")))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	// Start with a newline, as class numbers are only recognized at line starts
+	lines := []string{""}
+	var line []string
+	flush := func() {
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = line[:0]
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("malformed HTML: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if blockElements[strings.ToLower(t.Name.Local)] {
+				flush()
+			}
+		case xml.EndElement:
+			if blockElements[strings.ToLower(t.Name.Local)] {
+				flush()
+			}
+		case xml.CharData:
+			// This also turns non-breaking spaces into regular ones
+			line = append(line, strings.Fields(string(t))...)
+		}
+	}
+	flush()
+	return strings.Join(lines, "\n") + "\n", nil
+}
@@ -0,0 +1,314 @@
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flow/api/parse/diagnostic"
+	"flow/common/util"
+)
+
+// iCalendar exports of Quest schedules have an event per meeting of a class.
+// They are not produced by Quest itself, so we only assume that every event
+// mentions the class number in its description or summary, e.g. "Class Nbr: 5211".
+
+var (
+	icsClassNumberRegexp = regexp.MustCompile(`(?i)class\s*(?:nbr|number|#)\s*:?\s*\(?(\d{4,8})\)?`)
+	// Sections are named like in Quest, e.g. "CS 341 - LEC 001" or "Section: LEC 001"
+	icsSectionRegexp    = regexp.MustCompile(`(?m)(?:\s-\s|^Section:\s*)([A-Z]{3}) (\d{3})\s*$`)
+	icsInstructorRegexp = regexp.MustCompile(`(?im)^instructors?:\s*(.+)$`)
+	icsUntilRegexp      = regexp.MustCompile(`(?:^|;)UNTIL=([0-9TZ]+)`)
+	icsByDayRegexp      = regexp.MustCompile(`(?:^|;)BYDAY=([A-Z,]+)`)
+)
+
+// icsWeekdays maps RRULE weekdays to weekday codes as in section_meeting.
+var icsWeekdays = map[string]string{
+	"MO": "M", "TU": "T", "WE": "W", "TH": "Th", "FR": "F", "SA": "S", "SU": "Su",
+}
+
+type icsEvent struct {
+	Summary     string
+	Description string
+	Location    string
+	// Start and End are DTSTART and DTEND values, e.g. 20190904T083000
+	Start string
+	End   string
+	Rule  string
+}
+
+// unfoldIcs joins lines split according to RFC 5545, section 3.1.
+func unfoldIcs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+	return strings.Split(text, "\n")
+}
+
+// splitIcsLine splits a content line into its name and value.
+// Parameters such as TZID are dropped.
+func splitIcsLine(line string) (string, string) {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			name, _, _ := strings.Cut(line[:i], ";")
+			return strings.ToUpper(name), line[i+1:]
+		}
+	}
+	return strings.ToUpper(line), ""
+}
+
+var icsTextReplacer = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func parseIcsEvents(text string) ([]icsEvent, error) {
+	var events []icsEvent
+	var event *icsEvent
+	for _, line := range unfoldIcs(text) {
+		name, value := splitIcsLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			if event != nil {
+				return nil, fmt.Errorf("nested VEVENT")
+			}
+			event = &icsEvent{}
+		case name == "END" && value == "VEVENT":
+			if event == nil {
+				return nil, fmt.Errorf("unexpected END:VEVENT")
+			}
+			events = append(events, *event)
+			event = nil
+		case event == nil:
+		case name == "SUMMARY":
+			event.Summary = icsTextReplacer.Replace(value)
+		case name == "DESCRIPTION":
+			event.Description = icsTextReplacer.Replace(value)
+		case name == "LOCATION":
+			event.Location = strings.TrimSpace(icsTextReplacer.Replace(value))
+		case name == "DTSTART":
+			event.Start = value
+		case name == "DTEND":
+			event.End = value
+		case name == "RRULE":
+			event.Rule = value
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// icsEventTerm returns the term in which the event starts.
+func icsEventTerm(event icsEvent) (int, error) {
+	if len(event.Start) < len("20060102") {
+		return 0, fmt.Errorf("event has no start")
+	}
+	start, err := time.Parse("20060102", event.Start[:8])
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a date: %w", event.Start, err)
+	}
+	// Quest ids are positive and have at most four digits
+	if start.Year() < 1900 || start.Year() >= 2900 {
+		return 0, fmt.Errorf("%s is out of range", event.Start)
+	}
+	return util.DateToTermId(start), nil
+}
+
+// parseIcsTime parses a DATE-TIME value, which is in UTC if it ends with Z
+// and otherwise in local time, which we assume to be that of the university.
+func parseIcsTime(value string) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		parsed, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s is not a time: %w", value, err)
+		}
+		return parsed.In(util.UniversityLocation), nil
+	}
+	parsed, err := time.Parse("20060102T150405", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a time: %w", value, err)
+	}
+	return parsed, nil
+}
+
+func toDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func secondsSinceMidnight(t time.Time) int {
+	return t.Hour()*3600 + t.Minute()*60
+}
+
+// icsMeeting converts an event into a meeting that takes place
+// on the weekdays of its recurrence until the end of the recurrence.
+func icsMeeting(event icsEvent) (Meeting, error) {
+	meeting := Meeting{Location: event.Location}
+	if submatch := icsInstructorRegexp.FindStringSubmatch(event.Description); submatch != nil {
+		meeting.Instructor = strings.TrimSpace(submatch[1])
+	}
+
+	// All-day events are dates without times
+	if len(event.Start) == len("20060102") {
+		start, err := time.Parse("20060102", event.Start)
+		if err != nil {
+			return meeting, fmt.Errorf("%s is not a date: %w", event.Start, err)
+		}
+		meeting.StartDate, meeting.EndDate = start, start
+	} else {
+		start, err := parseIcsTime(event.Start)
+		if err != nil {
+			return meeting, err
+		}
+		// Events without an end take no time
+		end := start
+		if event.End != "" {
+			end, err = parseIcsTime(event.End)
+			if err != nil {
+				return meeting, err
+			}
+		}
+		meeting.StartSeconds = util.IntToPointer(secondsSinceMidnight(start))
+		meeting.EndSeconds = util.IntToPointer(secondsSinceMidnight(end))
+		meeting.StartDate, meeting.EndDate = toDate(start), toDate(start)
+		meeting.Days = []string{util.WeekdayCodes[(start.Weekday()+6)%7]}
+	}
+
+	if submatch := icsUntilRegexp.FindStringSubmatch(event.Rule); submatch != nil {
+		until := submatch[1]
+		if len(until) > len("20060102") {
+			parsed, err := parseIcsTime(until)
+			if err != nil {
+				return meeting, err
+			}
+			until = parsed.Format("20060102")
+		}
+		end, err := time.Parse("20060102", until)
+		if err != nil {
+			return meeting, fmt.Errorf("%s is not a date: %w", until, err)
+		}
+		meeting.EndDate = end
+	}
+	if submatch := icsByDayRegexp.FindStringSubmatch(event.Rule); submatch != nil {
+		meeting.Days = nil
+		for _, day := range strings.Split(submatch[1], ",") {
+			if code, ok := icsWeekdays[day]; ok {
+				meeting.Days = append(meeting.Days, code)
+			}
+		}
+	}
+	return meeting, nil
+}
+
+// eventLine describes an event that is not a class meeting.
+func eventLine(event icsEvent) string {
+	return strings.TrimSpace(event.Summary + " " + event.Start)
+}
+
+// parseIcs returns a summary for each term with classes in the calendar.
+// Every class is in the term of its first meeting. A calendar may span several terms,
+// so a term named anywhere in it is only used for events without a usable start.
+func parseIcs(text string, report *diagnostic.Report) ([]*Summary, error) {
+	events, err := parseIcsEvents(text)
+	if err != nil {
+		return nil, fmt.Errorf("malformed iCalendar: %w", err)
+	}
+	eventTerm := func(event icsEvent) (int, error) {
+		term, err := icsEventTerm(event)
+		if err == nil || !termRegexp.MatchString(text) {
+			return term, err
+		}
+		return extractTerm(text)
+	}
+
+	// Classes are listed in order of their first meeting in the file,
+	// with locations of all of their meetings, as with the text parser.
+	var summaries []*Summary
+	summaryByTerm := make(map[int]*Summary)
+	classSummary := make(map[int]*Summary)
+	classIndex := make(map[int]int)
+	seenLocations := make(map[int]map[string]bool)
+	for _, event := range events {
+		submatch := icsClassNumberRegexp.FindStringSubmatch(event.Description)
+		if submatch == nil {
+			submatch = icsClassNumberRegexp.FindStringSubmatch(event.Summary)
+		}
+		if submatch == nil {
+			// This is usually not a class meeting, e.g. a holiday
+			report.Unmatched(eventLine(event))
+			continue
+		}
+		cn, err := strconv.Atoi(submatch[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a class number: %w", submatch[1], err)
+		}
+		if cn == 0 {
+			return nil, fmt.Errorf("%s is not a class number", submatch[1])
+		}
+
+		summary, ok := classSummary[cn]
+		if !ok {
+			term, err := eventTerm(event)
+			if err != nil {
+				return nil, fmt.Errorf("extracting term of class %d: %w", cn, err)
+			}
+			summary, ok = summaryByTerm[term]
+			if !ok {
+				summary = &Summary{TermId: term}
+				summaryByTerm[term] = summary
+				summaries = append(summaries, summary)
+			}
+			classSummary[cn] = summary
+			classIndex[cn] = len(summary.Classes)
+			seenLocations[cn] = make(map[string]bool)
+			report.Matched++
+
+			class := Class{Number: cn}
+			submatch := icsSectionRegexp.FindStringSubmatch(event.Summary)
+			if submatch == nil {
+				submatch = icsSectionRegexp.FindStringSubmatch(event.Description)
+			}
+			if submatch != nil {
+				class.Component, class.Section = submatch[1], submatch[2]
+			}
+			summary.Classes = append(summary.Classes, class)
+		}
+		class := &summary.Classes[classIndex[cn]]
+
+		meeting, err := icsMeeting(event)
+		if err != nil {
+			return nil, fmt.Errorf("parsing class %d: %w", cn, err)
+		}
+		class.Meetings = append(class.Meetings, meeting)
+
+		if event.Location == "" || seenLocations[cn][event.Location] {
+			continue
+		}
+		seenLocations[cn][event.Location] = true
+		if class.Location != "" {
+			class.Location += ", "
+		}
+		class.Location += event.Location
+	}
+
+	// Without classes, we can still tell the term, which is needed to report errors
+	if len(summaries) == 0 {
+		var term int
+		if len(events) > 0 {
+			term, err = eventTerm(events[0])
+		} else if termRegexp.MatchString(text) {
+			term, err = extractTerm(text)
+		} else {
+			err = fmt.Errorf("term id not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extracting term: %w", err)
+		}
+		summaries = append(summaries, &Summary{TermId: term})
+	}
+	return summaries, nil
+}
@@ -0,0 +1,280 @@
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"flow/common/util"
+)
+
+type Meeting struct {
+	// Days are weekday codes as in section_meeting, e.g. ["T", "Th"]
+	Days []string
+	// Seconds since midnight, or nil if the time is TBA
+	StartSeconds *int
+	EndSeconds   *int
+	// Location is empty if the schedule leaves it blank
+	Location   string
+	Instructor string
+	// Dates are zero if the schedule omits them, as the class view does
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// Quest lists classes in one of two views.
+//
+// The list view has a row per meeting, with a cell per line:
+//
+//	5867
+//	001
+//	LEC
+//	MW 8:30AM - 9:50AM
+//	MC 2017
+//	Anna Lubiw
+//	04/09/2019 - 03/12/2019
+//
+// Further meetings of the same class leave the first three cells blank.
+// Blank cells become whitespace-only lines, but only if the browser keeps them.
+//
+// The class view has a row per class, with all of its times, then all of its rooms:
+//
+//	ME 235-101
+//	(4897)
+//	Materials Science & Eng (TUT)
+//	W 9:30AM - 10:20AM
+//	F 9:30AM - 10:20AM
+//	MC 4064
+//	DWE 2527
+//	Staff
+var (
+	sectionRegexp     = regexp.MustCompile(`^\d{3}$`)
+	componentRegexp   = regexp.MustCompile(`^[A-Z]{3}$`)
+	courseRowRegexp   = regexp.MustCompile(`-(\d{3})$`)
+	descriptionRegexp = regexp.MustCompile(`\(([A-Z]{3})\)$`)
+	timesRegexp       = regexp.MustCompile(`^([MTWFSuh]+) (\d{1,2}:\d{2}[AP]M) - (\d{1,2}:\d{2}[AP]M)$`)
+	datesRegexp       = regexp.MustCompile(`^([\d/]+) - ([\d/]+)$`)
+)
+
+// parseDays splits weekdays like "TTh" into codes like ["T", "Th"].
+func parseDays(days string) ([]string, error) {
+	var codes []string
+	for i := 0; i < len(days); {
+		switch {
+		case strings.HasPrefix(days[i:], "Th"), strings.HasPrefix(days[i:], "Su"):
+			codes = append(codes, days[i:i+2])
+			i += 2
+		case strings.ContainsRune("MTWFS", rune(days[i])):
+			codes = append(codes, days[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("%q is not a list of weekdays", days)
+		}
+	}
+	return codes, nil
+}
+
+func parseClockTime(clock string) (int, error) {
+	parsed, err := time.Parse("3:04PM", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time: %w", clock, err)
+	}
+	return parsed.Hour()*3600 + parsed.Minute()*60, nil
+}
+
+func isTimes(line string) bool {
+	return line == "TBA" || timesRegexp.MatchString(line)
+}
+
+// parseTimes parses days and times like "TTh 2:30PM - 3:50PM" into meeting.
+// Times that are to be announced are left blank.
+func parseTimes(times string, meeting *Meeting) error {
+	if times == "" || times == "TBA" {
+		return nil
+	}
+	submatches := timesRegexp.FindStringSubmatch(times)
+	if submatches == nil {
+		return fmt.Errorf("%q is not a meeting time", times)
+	}
+	days, err := parseDays(submatches[1])
+	if err != nil {
+		return err
+	}
+	start, err := parseClockTime(submatches[2])
+	if err != nil {
+		return err
+	}
+	end, err := parseClockTime(submatches[3])
+	if err != nil {
+		return err
+	}
+	meeting.Days = days
+	meeting.StartSeconds = util.IntToPointer(start)
+	meeting.EndSeconds = util.IntToPointer(end)
+	return nil
+}
+
+// Quest has formatted dates as MM/DD/YYYY, DD/MM/YYYY and YYYY/MM/DD over the years.
+var dateLayouts = []string{"2006/01/02", "02/01/2006", "01/02/2006"}
+
+// parseDates parses a date range like "04/09/2019 - 03/12/2019".
+// Day and month are told apart by which order gives a valid range,
+// or failing that, a range that starts in the given term.
+func parseDates(dates string, termId int) (time.Time, time.Time, error) {
+	submatches := datesRegexp.FindStringSubmatch(dates)
+	if submatches == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%q is not a date range", dates)
+	}
+
+	var fallbackStart, fallbackEnd time.Time
+	for _, layout := range dateLayouts {
+		start, err := time.Parse(layout, submatches[1])
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(layout, submatches[2])
+		if err != nil || end.Before(start) {
+			continue
+		}
+		if util.DateToTermId(start) == termId {
+			return start, end, nil
+		}
+		if fallbackStart.IsZero() {
+			fallbackStart, fallbackEnd = start, end
+		}
+	}
+	if fallbackStart.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("%q is not a date range", dates)
+	}
+	return fallbackStart, fallbackEnd, nil
+}
+
+// parseListView fills in details of the class from the lines after its number.
+func parseListView(lines []string, termId int, class *Class) error {
+	class.Section = lines[0]
+	if len(lines) > 1 && componentRegexp.MatchString(lines[1]) {
+		class.Component = lines[1]
+	}
+
+	for i := 2; i < len(lines); {
+		var meeting Meeting
+		// Blank times may have been dropped along with other blank cells
+		if isTimes(lines[i]) || lines[i] == "" {
+			err := parseTimes(lines[i], &meeting)
+			if err != nil {
+				return err
+			}
+			i++
+		}
+
+		// Every meeting ends with its dates, which are easy to recognize.
+		// Before them are the room and any number of instructors, one per line.
+		end := i
+		for end < len(lines) && !datesRegexp.MatchString(lines[end]) {
+			end++
+		}
+		if end == len(lines) {
+			break
+		}
+		if i < end {
+			meeting.Location = lines[i]
+			meeting.Instructor = strings.Join(lines[i+1:end], " ")
+		}
+		var err error
+		meeting.StartDate, meeting.EndDate, err = parseDates(lines[end], termId)
+		if err != nil {
+			return err
+		}
+		class.Meetings = append(class.Meetings, meeting)
+
+		// Further meetings start with blank cells, then their times
+		i = end + 1
+		for i < len(lines) && lines[i] == "" {
+			i++
+		}
+		if i == len(lines) || !timesRegexp.MatchString(lines[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+// parseClassView fills in details of the class from the lines around its number.
+func parseClassView(before, lines []string, class *Class) error {
+	if len(before) > 0 {
+		if submatches := courseRowRegexp.FindStringSubmatch(before[len(before)-1]); submatches != nil {
+			class.Section = submatches[1]
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if submatches := descriptionRegexp.FindStringSubmatch(lines[0]); submatches != nil {
+		class.Component = submatches[1]
+	}
+
+	times := 1
+	for times < len(lines) && isTimes(lines[times]) {
+		times++
+	}
+	// A class without a meeting time still has a blank cell for it
+	if times == 1 && len(lines) > 1 && lines[1] == "" {
+		times++
+	}
+	rooms := lines[times:]
+	if len(rooms) > times-1 {
+		rooms = rooms[:times-1]
+	}
+
+	// The instructors of all meetings share a cell after the rooms,
+	// with a line per instructor, all but the last ending in a comma.
+	var instructors []string
+	for i := 2*times - 1; i < len(lines); i++ {
+		instructors = append(instructors, lines[i])
+		if !strings.HasSuffix(lines[i], ",") {
+			break
+		}
+	}
+
+	for i, line := range lines[1:times] {
+		meeting := Meeting{Instructor: strings.Join(instructors, " ")}
+		err := parseTimes(line, &meeting)
+		if err != nil {
+			return err
+		}
+		if i < len(rooms) {
+			meeting.Location = rooms[i]
+		}
+		class.Meetings = append(class.Meetings, meeting)
+	}
+	return nil
+}
+
+func trimLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines
+}
+
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range trimLines(text) {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseDetails fills in the section, component and meetings of the class
+// from the text before and after the line with its number.
+func parseDetails(before, after string, termId int, class *Class) error {
+	lines := trimLines(after)
+	if len(lines) > 0 && sectionRegexp.MatchString(lines[0]) {
+		return parseListView(lines, termId, class)
+	}
+	return parseClassView(nonEmptyLines(before), lines, class)
+}
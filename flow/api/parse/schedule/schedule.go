@@ -6,12 +6,19 @@ import (
 	"strconv"
 	"strings"
 
+	"flow/api/parse/diagnostic"
 	"flow/common/util"
 )
 
 type Class struct {
-	Number   int
+	Number int
+	// Location lists the distinct locations of all meetings, e.g. "MC 4064, DWE 2527"
 	Location string
+	// Section is the section number, e.g. "001"
+	Section string
+	// Component is the kind of section, e.g. "LEC"
+	Component string
+	Meetings  []Meeting
 }
 
 type Summary struct {
@@ -24,6 +31,11 @@ type Summary struct {
 var (
 	termRegexp = regexp.MustCompile(`(Spring|Fall|Winter)\s+(\d{4})`)
 
+	// Quest starts the schedule of every term with a heading line like
+	// "Fall 2019 | Undergraduate | University of Waterloo".
+	// Students may paste several of these pages at once.
+	termHeadingRegexp = regexp.MustCompile(`(?m)^\s*(Spring|Fall|Winter)\s+(\d{4})\s*\|`)
+
 	// Class numbers are *the* four or five digit sequences
 	// which occur on a separate line, perhaps parenthesized.
 	// To be safe, we pre-emptively handle sequences up to length 8.
@@ -38,6 +50,7 @@ var (
 
 type match struct {
 	pos int
+	end int
 	val string
 }
 
@@ -56,12 +69,52 @@ func extractTerm(text string) (int, error) {
 	}
 }
 
+// termSpan is the part of the text that lists the classes of a term.
+type termSpan struct {
+	termId int
+	start  int
+	end    int
+}
+
+// extractTerms splits the text at term headings. Classes before the first heading
+// belong to the first term. Without headings, the whole text belongs to the first
+// term mentioned anywhere, as in schedules from before Quest had headings.
+func extractTerms(text string) ([]termSpan, error) {
+	submatches := termHeadingRegexp.FindAllStringSubmatchIndex(text, -1)
+	if submatches == nil {
+		term, err := extractTerm(text)
+		if err != nil {
+			return nil, err
+		}
+		return []termSpan{{termId: term, start: 0, end: len(text)}}, nil
+	}
+
+	var spans []termSpan
+	for _, submatch := range submatches {
+		season := text[submatch[2]:submatch[3]]
+		year := text[submatch[4]:submatch[5]]
+		term, err := util.TermSeasonYearToId(season, year)
+		if err != nil {
+			return nil, fmt.Errorf("\"%s %s\" is not a term: %w", season, year, err)
+		}
+		if len(spans) == 0 {
+			spans = append(spans, termSpan{termId: term, start: 0})
+		} else if spans[len(spans)-1].termId != term {
+			spans[len(spans)-1].end = submatch[0]
+			spans = append(spans, termSpan{termId: term, start: submatch[0]})
+		}
+	}
+	spans[len(spans)-1].end = len(text)
+	return spans, nil
+}
+
 func extractClassNumbers(text string) ([]match, error) {
 	submatches := classNumberRegexp.FindAllStringSubmatchIndex(text, -1)
 	matches := make([]match, len(submatches))
 	for i, submatch := range submatches {
 		matches[i] = match{
 			pos: submatch[0],
+			end: submatch[1],
 			val: text[submatch[2]:submatch[3]],
 		}
 	}
@@ -80,11 +133,82 @@ func extractClassrooms(text string) ([]match, error) {
 	return matches, nil
 }
 
-func Parse(text string) (*Summary, error) {
-	term, err := extractTerm(text)
+// Parse extracts schedules from text copied out of Quest,
+// from Quest's printable HTML page or from an iCalendar export.
+// The format is detected from the start of the text.
+// There is a summary for each term with classes, in order of appearance.
+// The report is returned even if parsing fails, as that is when it is most useful.
+func Parse(text string) ([]*Summary, *diagnostic.Report, error) {
+	report := &diagnostic.Report{}
+	summaries, err := parse(text, report)
+	if err != nil {
+		report.Fail(err)
+		return nil, report, err
+	}
+	report.Score()
+	return summaries, report, nil
+}
+
+func parse(text string, report *diagnostic.Report) ([]*Summary, error) {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))
+	switch {
+	case strings.HasPrefix(strings.ToUpper(trimmed), "BEGIN:VCALENDAR"):
+		return parseIcs(trimmed, report)
+	case strings.HasPrefix(trimmed, "<"):
+		converted, err := htmlToText(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		text = converted
+	}
+
+	summaries, err := parseText(text)
+	if err != nil {
+		return nil, err
+	}
+	diagnoseText(text, summaries, report)
+	return summaries, nil
+}
+
+func parseText(text string) ([]*Summary, error) {
+	spans, err := extractTerms(text)
 	if err != nil {
 		return nil, fmt.Errorf("extracting term: %w", err)
 	}
+
+	// The same term may be pasted more than once, so we merge its spans
+	var summaries []*Summary
+	summaryByTerm := make(map[int]*Summary)
+	for _, span := range spans {
+		classes, err := parseClasses(text[span.start:span.end], span.termId)
+		if err != nil {
+			return nil, err
+		}
+		summary, ok := summaryByTerm[span.termId]
+		if !ok {
+			summary = &Summary{TermId: span.termId}
+			summaryByTerm[span.termId] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.Classes = append(summary.Classes, classes...)
+	}
+
+	// Headings of terms without classes are not worth importing,
+	// unless there are no classes at all, which is then reported as an error.
+	var nonEmpty []*Summary
+	for _, summary := range summaries {
+		if len(summary.Classes) > 0 {
+			nonEmpty = append(nonEmpty, summary)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return summaries[:1], nil
+	}
+	return nonEmpty, nil
+}
+
+// parseClasses parses classes from text that belongs to a single term.
+func parseClasses(text string, term int) ([]Class, error) {
 	classNumbers, err := extractClassNumbers(text)
 	if err != nil {
 		return nil, fmt.Errorf("extracting class numbers: %w", err)
@@ -102,6 +226,9 @@ func Parse(text string) (*Summary, error) {
 		if err != nil {
 			return nil, fmt.Errorf("%s is not a class number: %w", cnMatch.val, err)
 		}
+		if cn == 0 {
+			return nil, fmt.Errorf("%s is not a class number", cnMatch.val)
+		}
 
 		// Determine the end position for this class's context.
 		// It ends where the NEXT class number begins.
@@ -138,14 +265,20 @@ func Parse(text string) (*Summary, error) {
 			}
 		}
 
-		classes = append(classes, Class{
+		class := Class{
 			Number:   cn,
 			Location: strings.Join(uniqueLocs, ", "),
-		})
+		}
+		prevEnd := 0
+		if i > 0 {
+			prevEnd = classNumbers[i-1].end
+		}
+		err = parseDetails(text[prevEnd:cnMatch.pos], text[cnMatch.end:nextPos], term, &class)
+		if err != nil {
+			return nil, fmt.Errorf("parsing class %d: %w", cn, err)
+		}
+		classes = append(classes, class)
 	}
 
-	return &Summary{
-		TermId:  term,
-		Classes: classes,
-	}, nil
+	return classes, nil
 }
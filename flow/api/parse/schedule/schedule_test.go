@@ -4,96 +4,390 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"flow/common/util"
 )
 
+// Meeting details are checked separately by TestParseMeetings
+var ignoreDetails = cmpopts.IgnoreFields(Class{}, "Section", "Component", "Meetings")
+
 func TestParseSchedule(t *testing.T) {
 	tests := []struct {
 		name string
-		want *Summary
+		// formats are the extensions of fixtures for the same schedule
+		formats []string
+		want    *Summary
 	}{
 		// This schedule is perfectly normal.
 		{
 			"normal",
+			[]string{"txt"},
 			&Summary{
 				TermId: 1199,
 				Classes: []Class{
-					{4896, "MC 2038"}, {4897, "MC 4064, DWE 2527"}, {4899, "E3 2119"},
-					{4741, "CPH 3681"}, {4742, "CPH 3681"}, {5003, "CPH 3681"},
-					{4747, "CPH 3681"}, {4748, "CPH 3681"}, {7993, "MC 2034"},
-					{7994, "CPH 3681"}, {7995, "CPH 1346"}, {4751, "CPH 3681"},
-					{4752, "CPH 3681"},
+					{Number: 4896, Location: "MC 2038"}, {Number: 4897, Location: "MC 4064, DWE 2527"},
+					{Number: 4899, Location: "E3 2119"}, {Number: 4741, Location: "CPH 3681"},
+					{Number: 4742, Location: "CPH 3681"}, {Number: 5003, Location: "CPH 3681"},
+					{Number: 4747, Location: "CPH 3681"}, {Number: 4748, Location: "CPH 3681"},
+					{Number: 7993, Location: "MC 2034"}, {Number: 7994, Location: "CPH 3681"},
+					{Number: 7995, Location: "CPH 1346"}, {Number: 4751, Location: "CPH 3681"},
+					{Number: 4752, Location: "CPH 3681"},
 				},
 			},
 		},
 		// This schedule does not have parentheses around class numbers.
+		// It is also given as printable HTML and as an iCalendar export.
 		{
 			"noparen",
+			[]string{"txt", "html", "ics"},
 			&Summary{
 				TermId: 1199,
 				Classes: []Class{
-					{5211, "E7 2317"}, {8052, "RCH 101"}, {9289, "MC 2034"},
-					{6394, "TBA"}, {5867, "MC 2017"}, {6321, "TBA"},
-					{6205, "AL 124"}, {7253, "DC 1351"}, {7254, "DC 1351"},
+					{Number: 5211, Location: "E7 2317"}, {Number: 8052, Location: "RCH 101"},
+					{Number: 9289, Location: "MC 2034"}, {Number: 6394, Location: "TBA"},
+					{Number: 5867, Location: "MC 2017"}, {Number: 6321, Location: "TBA"},
+					{Number: 6205, Location: "AL 124"}, {Number: 7253, Location: "DC 1351"},
+					{Number: 7254, Location: "DC 1351"},
 				},
 			},
 		},
 		// This schedule is old (carried over from Flow 1.0)
 		{
 			"old",
+			[]string{"txt"},
 			&Summary{
 				TermId: 1135,
 				Classes: []Class{
-					{3370, "MC   4040"}, {3077, "QNC 1502"}, {3078, "QNC 1502"},
-					{3166, "TBA"}, {2446, "STP 105"}, {4106, "RCH   307"},
-					{4107, "MC   2038"}, {4108, "MC   2038"}, {4111, "TBA"},
-					{4117, "MC   2038"}, {4118, "TBA"}, {4110, "TBA"},
+					{Number: 3370, Location: "MC   4040"}, {Number: 3077, Location: "QNC 1502"},
+					{Number: 3078, Location: "QNC 1502"}, {Number: 3166, Location: "TBA"},
+					{Number: 2446, Location: "STP 105"}, {Number: 4106, Location: "RCH   307"},
+					{Number: 4107, Location: "MC   2038"}, {Number: 4108, Location: "MC   2038"},
+					{Number: 4111, Location: "TBA"}, {Number: 4117, Location: "MC   2038"},
+					{Number: 4118, Location: "TBA"}, {Number: 4110, Location: "TBA"},
 				},
 			},
 		},
 		// This schedule has an abnormal amount of whitespace
 		{
 			"whitespace",
+			[]string{"txt"},
 			&Summary{
 				TermId: 1199,
 				Classes: []Class{
-					{4669, "E5 3102, E5 3101"}, {4658, "E5 3101"}, {4660, "DWE 3518"},
-					{4699, "CPH 1346"}, {4655, "E5 3102, E5 3101"}, {4656, "MC 4063"},
-					{4661, "E5 3101, E5 3102"}, {4662, "E5 3101"}, {4850, "E3 3164"},
-					{4664, "E5 3101, E5 3102"}, {4666, "MC 4060"}, {4936, "E2 2363"},
-					{4639, "E5 3101"}, {4668, "EV3 4412"}, {7634, "TBA"},
+					{Number: 4669, Location: "E5 3102, E5 3101"}, {Number: 4658, Location: "E5 3101"},
+					{Number: 4660, Location: "DWE 3518"}, {Number: 4699, Location: "CPH 1346"},
+					{Number: 4655, Location: "E5 3102, E5 3101"}, {Number: 4656, Location: "MC 4063"},
+					{Number: 4661, Location: "E5 3101, E5 3102"}, {Number: 4662, Location: "E5 3101"},
+					{Number: 4850, Location: "E3 3164"}, {Number: 4664, Location: "E5 3101, E5 3102"},
+					{Number: 4666, Location: "MC 4060"}, {Number: 4936, Location: "E2 2363"},
+					{Number: 4639, Location: "E5 3101"}, {Number: 4668, Location: "EV3 4412"},
+					{Number: 7634, Location: "TBA"},
 				},
 			},
 		},
 		// This schedule has class codes longer than 4 digits
 		{
 			"long-classnumber",
+			[]string{"txt"},
 			&Summary{
 				TermId: 1219,
 				Classes: []Class{
-					{4262, "ONLN - Online"}, {11810, "ONLN - Online"}, {9336, "ONLN - Online"},
-					{6336, "ONLN - Online"}, {6367, "ONLN - Online"}, {10692, "ONLN - Online"},
-					{10310, "ONLN - Online"}, {8204, "ONLN - Online"}, {10376, "ONLN - Online"},
+					{Number: 4262, Location: "ONLN - Online"}, {Number: 11810, Location: "ONLN - Online"},
+					{Number: 9336, Location: "ONLN - Online"}, {Number: 6336, Location: "ONLN - Online"},
+					{Number: 6367, Location: "ONLN - Online"}, {Number: 10692, Location: "ONLN - Online"},
+					{Number: 10310, Location: "ONLN - Online"}, {Number: 8204, Location: "ONLN - Online"},
+					{Number: 10376, Location: "ONLN - Online"},
 				},
 			},
 		},
 	}
+	for _, tt := range tests {
+		for _, format := range tt.formats {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				path := fmt.Sprintf("testdata/schedule-%s.%s", tt.name, format)
+				bytes, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatalf("opening testdata: %v", err)
+				}
+				got, report, err := Parse(string(bytes))
+				if err != nil {
+					t.Fatalf("parsing: %v", err)
+				}
+				// Fixtures are exactly what the parser expects
+				if report.Confidence != 1 {
+					t.Errorf("Expected full confidence, but got %+v", report)
+				}
+				want := []*Summary{tt.want}
+				if !cmp.Equal(want, got, ignoreDetails) {
+					diff := cmp.Diff(want, got, ignoreDetails)
+					t.Fatalf("mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestParseIcsTerm(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []*Summary
+	}{
+		{
+			// The start of the event puts it in Fall 2020, whatever the calendar is named
+			"start",
+			"BEGIN:VCALENDAR\r\nX-WR-CALNAME:Spring 2021\r\nBEGIN:VEVENT\r\n" +
+				"DTSTART:20201215T090000\r\nSUMMARY:CS 135 - LEC 001 (Class Nbr:\r\n  4262)\r\n" +
+				"LOCATION:ONLN - Online\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			[]*Summary{{TermId: 1209, Classes: []Class{{Number: 4262, Location: "ONLN - Online"}}}},
+		},
+		{
+			// Without events, the calendar name is all there is
+			"name",
+			"BEGIN:VCALENDAR\r\nX-WR-CALNAME:Spring 2021\r\nEND:VCALENDAR\r\n",
+			[]*Summary{{TermId: 1215}},
+		},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			path := fmt.Sprintf("testdata/schedule-%s.txt", tt.name)
-			bytes, err := ioutil.ReadFile(path)
+			got, _, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if !cmp.Equal(tt.want, got, ignoreDetails) {
+				t.Fatalf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got, ignoreDetails))
+			}
+		})
+	}
+}
+
+func TestParseMultiTerm(t *testing.T) {
+	fall := &Summary{
+		TermId: 1199,
+		Classes: []Class{
+			{Number: 5211, Location: "E7 2317"}, {Number: 8052, Location: "RCH 101"},
+			{Number: 9289, Location: "MC 2034"}, {Number: 6394, Location: "TBA"},
+			{Number: 5867, Location: "MC 2017"}, {Number: 6321, Location: "TBA"},
+			{Number: 6205, Location: "AL 124"}, {Number: 7253, Location: "DC 1351"},
+			{Number: 7254, Location: "DC 1351"},
+		},
+	}
+	winter := &Summary{
+		TermId: 1201,
+		Classes: []Class{
+			{Number: 5725, Location: "MC 4020"}, {Number: 5798, Location: "MC 2035"},
+			{Number: 5812, Location: "TBA"},
+		},
+	}
+
+	bytes, err := ioutil.ReadFile("testdata/schedule-multiterm.txt")
+	if err != nil {
+		t.Fatalf("opening testdata: %v", err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want []*Summary
+	}{
+		{"fall then winter", string(bytes), []*Summary{fall, winter}},
+		// Pasting the same term twice does not create another summary
+		{"fall twice", string(bytes) + "\nFall 2019 | Undergraduate | University of Waterloo\n", []*Summary{fall, winter}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if !cmp.Equal(tt.want, got, ignoreDetails) {
+				t.Fatalf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got, ignoreDetails))
+			}
+		})
+	}
+}
+
+func TestParseIcsMultiTerm(t *testing.T) {
+	text := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20190904T083000\r\nDESCRIPTION:Class Nbr: 5867\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20200107T100000\r\nDESCRIPTION:Class Nbr: 5725\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20191204T090000\r\nDESCRIPTION:Class Nbr: 5867\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	want := []*Summary{
+		{TermId: 1199, Classes: []Class{{Number: 5867}}},
+		{TermId: 1201, Classes: []Class{{Number: 5725}}},
+	}
+	got, _, err := Parse(text)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	if !cmp.Equal(want, got, ignoreDetails) {
+		t.Fatalf("mismatch (-want +got):\n%s", cmp.Diff(want, got, ignoreDetails))
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	// Quest changed how it formats times, so the meeting is not parsed
+	text := `
+Fall 2019 | Undergraduate | University of Waterloo
+Class Nbr	Section	Component	Days & Times	Room	Instructor	Start/End Date
+5211
+001
+LEC
+Th 4:00 PM to 6:50 PM
+E7 2317
+Swaroopa Reddy
+04/09/2019 - 03/12/2019
+`
+	_, report, err := Parse(text)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	wantUnmatched := []string{"Th 4:00 PM to 6:50 PM"}
+	if !cmp.Equal(wantUnmatched, report.UnmatchedLines) {
+		t.Errorf("unmatched lines mismatch (-want +got):\n%s", cmp.Diff(wantUnmatched, report.UnmatchedLines))
+	}
+	if report.Confidence != 0.5 {
+		t.Errorf("Expected confidence 0.5, but got %v", report.Confidence)
+	}
+
+	_, report, err = Parse("no term here")
+	if err == nil {
+		t.Fatalf("Expected an error without a term")
+	}
+	if report.Confidence != 0 {
+		t.Errorf("Expected zero confidence, but got %v", report.Confidence)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseMeetings(t *testing.T) {
+	lubiw := Class{
+		Number: 5867, Location: "MC 2017", Section: "001", Component: "LEC",
+		Meetings: []Meeting{{
+			Days:         []string{"M", "W"},
+			StartSeconds: util.IntToPointer(30600), EndSeconds: util.IntToPointer(35400),
+			Location: "MC 2017", Instructor: "Anna Lubiw",
+			StartDate: date(2019, 9, 4), EndDate: date(2019, 12, 3),
+		}},
+	}
+	tests := []struct {
+		fixture string
+		want    Class
+	}{
+		// Class view: times, then rooms, then instructors, without dates
+		{
+			"normal.txt",
+			Class{
+				Number: 4897, Location: "MC 4064, DWE 2527", Section: "101", Component: "TUT",
+				Meetings: []Meeting{
+					{
+						Days:         []string{"W"},
+						StartSeconds: util.IntToPointer(34200), EndSeconds: util.IntToPointer(37200),
+						Location: "MC 4064", Instructor: "Staff",
+					},
+					{
+						Days:         []string{"F"},
+						StartSeconds: util.IntToPointer(34200), EndSeconds: util.IntToPointer(37200),
+						Location: "DWE 2527", Instructor: "Staff",
+					},
+				},
+			},
+		},
+		{
+			"normal.txt",
+			Class{
+				Number: 4751, Location: "CPH 3681", Section: "001", Component: "LEC",
+				Meetings: []Meeting{{
+					Days:         []string{"M", "W", "F"},
+					StartSeconds: util.IntToPointer(45000), EndSeconds: util.IntToPointer(48000),
+					Location: "CPH 3681", Instructor: "F. Beylunioglu, Q. He",
+				}},
+			},
+		},
+		{
+			"whitespace.txt",
+			Class{
+				Number: 7634, Location: "TBA", Section: "006", Component: "PRJ",
+				Meetings: []Meeting{{Location: "TBA", Instructor: "W. Melek"}},
+			},
+		},
+		// List view: a row per meeting, with dates in various formats
+		{"noparen.txt", lubiw},
+		{"noparen.html", lubiw},
+		{"noparen.ics", lubiw},
+		{
+			"old.txt",
+			Class{
+				Number: 3166, Location: "TBA", Section: "201", Component: "LAB",
+				Meetings: []Meeting{{
+					Location: "TBA", Instructor: "Staff",
+					StartDate: date(2013, 5, 6), EndDate: date(2013, 7, 30),
+				}},
+			},
+		},
+		{
+			"long-classnumber.txt",
+			Class{
+				Number: 10310, Location: "ONLN - Online", Section: "081", Component: "LEC",
+				Meetings: []Meeting{{
+					Location: "ONLN - Online", Instructor: "Mohammad Mahmoud, Wentang Kuo, Yu-Ru Liu",
+					StartDate: date(2021, 9, 8), EndDate: date(2021, 12, 7),
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.fixture, tt.want.Number), func(t *testing.T) {
+			bytes, err := ioutil.ReadFile("testdata/schedule-" + tt.fixture)
 			if err != nil {
 				t.Fatalf("opening testdata: %v", err)
 			}
-			got, err := Parse(string(bytes))
+			summaries, _, err := Parse(string(bytes))
 			if err != nil {
 				t.Fatalf("parsing: %v", err)
 			}
-			if !cmp.Equal(tt.want, got) {
-				diff := cmp.Diff(tt.want, got)
-				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			for _, got := range summaries[0].Classes {
+				if got.Number != tt.want.Number {
+					continue
+				}
+				if !cmp.Equal(tt.want, got) {
+					t.Fatalf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+				}
+				return
 			}
+			t.Fatalf("class %d not found", tt.want.Number)
 		})
 	}
 }
+
+func TestParseDates(t *testing.T) {
+	tests := []struct {
+		dates      string
+		termId     int
+		start, end time.Time
+	}{
+		{"04/09/2019 - 03/12/2019", 1199, date(2019, 9, 4), date(2019, 12, 3)},
+		{"05/06/2013 - 07/30/2013", 1135, date(2013, 5, 6), date(2013, 7, 30)},
+		{"2021/09/08 - 2021/12/07", 1219, date(2021, 9, 8), date(2021, 12, 7)},
+		// Either order is a valid range, but only this one is in the term
+		{"05/09/2019 - 07/10/2019", 1195, date(2019, 5, 9), date(2019, 7, 10)},
+		{"05/09/2019 - 07/10/2019", 1199, date(2019, 9, 5), date(2019, 10, 7)},
+	}
+	for _, tt := range tests {
+		start, end, err := parseDates(tt.dates, tt.termId)
+		if err != nil {
+			t.Errorf("parsing %q: %v", tt.dates, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Expected %q to be %v - %v, but got %v - %v", tt.dates, tt.start, tt.end, start, end)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package transcript
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	nameRegexp = regexp.MustCompile(`(?m)^(\s*Name:\s+).*$`)
+	// Student and Ontario Education numbers
+	identifierRegexp = regexp.MustCompile(`((?:Student ID|Ontario Education Nbr):\s+)(\d+)`)
+	// Numeric grades follow the attempted and earned credits of a course
+	gradeRegexp = regexp.MustCompile(`(?m)(\d+\.\d{2}\s+\d+\.\d{2}\s+)(\d+)\s*$`)
+	// Term and cumulative GPA, faculty and program averages
+	averageRegexp = regexp.MustCompile(`((?:GPA|Average:?)\s+)(\d+\.\d{2})`)
+)
+
+func zeroDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if '0' <= r && r <= '9' {
+			return '0'
+		}
+		return r
+	}, s)
+}
+
+// zeroSubmatch replaces the digits of the second submatch of re with zeros.
+// Replacing digit by digit keeps the columns of the transcript aligned.
+func zeroSubmatch(re *regexp.Regexp, text string) string {
+	return re.ReplaceAllStringFunc(text, func(m string) string {
+		indices := re.FindStringSubmatchIndex(m)
+		return m[:indices[4]] + zeroDigits(m[indices[4]:indices[5]]) + m[indices[5]:]
+	})
+}
+
+// Anonymize removes the name, identifiers and grades of the student
+// from the text of a transcript, while keeping it just as parseable.
+func Anonymize(text string) string {
+	text = nameRegexp.ReplaceAllString(text, "${1}Doe, Jane")
+	text = zeroSubmatch(identifierRegexp, text)
+	text = zeroSubmatch(gradeRegexp, text)
+	text = zeroSubmatch(averageRegexp, text)
+	return text
+}
@@ -0,0 +1,59 @@
+package transcript
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"flow/api/parse/pdf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnonymize(t *testing.T) {
+	for _, tt := range transcriptTests {
+		t.Run(tt.name, func(t *testing.T) {
+			bytes, err := ioutil.ReadFile(fmt.Sprintf("testdata/transcript-%s.pdf", tt.name))
+			if err != nil {
+				t.Fatalf("reading pdf: %v", err)
+			}
+			text, err := pdf.ToText(bytes)
+			if err != nil {
+				t.Fatalf("converting: %v", err)
+			}
+
+			anonymized := Anonymize(text)
+			for _, secret := range []string{"Shynkevych", fmt.Sprint(tt.want.StudentNumber)} {
+				if strings.Contains(anonymized, secret) {
+					t.Errorf("Expected %q to be removed", secret)
+				}
+			}
+
+			got, report, err := Parse(anonymized)
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if report.Confidence != 1 {
+				t.Errorf("Expected full confidence, but got %+v", report)
+			}
+
+			// The anonymized transcript is the same, except for numeric grades
+			want := *tt.want
+			want.StudentNumber = 0
+			want.TermSummaries = nil
+			for _, termSummary := range tt.want.TermSummaries {
+				courses := make([]Course, len(termSummary.Courses))
+				for i, course := range termSummary.Courses {
+					course.Grade = zeroDigits(course.Grade)
+					courses[i] = course
+				}
+				termSummary.Courses = courses
+				want.TermSummaries = append(want.TermSummaries, termSummary)
+			}
+			if !cmp.Equal(&want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(&want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package transcript
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"flow/api/parse/pdf"
+	"flow/common/util"
+)
+
+// Fixtures used by the regression tests of the API
+const regtestFixtures = "../../../../regtest/fixtures"
+
+// FuzzParse seeds the corpus with the text of all transcripts we have.
+func FuzzParse(f *testing.F) {
+	paths, err := filepath.Glob("testdata/*.pdf")
+	if err != nil {
+		f.Fatalf("listing testdata: %v", err)
+	}
+	paths = append(paths, filepath.Join(regtestFixtures, "transcript.pdf"))
+	for _, path := range paths {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatalf("reading pdf: %v", err)
+		}
+		text, err := pdf.ToText(bytes)
+		if err != nil {
+			f.Fatalf("converting %s: %v", path, err)
+		}
+		f.Add(text)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		summary, report, err := Parse(text)
+		if report == nil {
+			t.Fatalf("Expected a report")
+		}
+		if report.Confidence < 0 || report.Confidence > 1 {
+			t.Errorf("Expected confidence between 0 and 1, but got %v", report.Confidence)
+		}
+		if err != nil {
+			if report.Confidence != 0 {
+				t.Errorf("Expected no confidence in a failed parse, but got %v", report.Confidence)
+			}
+			return
+		}
+
+		for _, termSummary := range summary.TermSummaries {
+			if !util.IsValidTermId(termSummary.TermId) {
+				t.Errorf("Invalid term id %d", termSummary.TermId)
+			}
+			if termSummary.Level == "" {
+				t.Errorf("Term %d has no level", termSummary.TermId)
+			}
+			for _, course := range termSummary.Courses {
+				if course.Code == "" {
+					t.Errorf("Course without code in term %d", termSummary.TermId)
+				}
+			}
+		}
+	})
+}
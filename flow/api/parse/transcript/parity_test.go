@@ -0,0 +1,30 @@
+//go:build !purego
+
+package transcript
+
+import (
+	"testing"
+
+	"flow/api/parse/pdf"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// The pure Go backend lays out text differently from poppler in places,
+// but both must produce the same transcript summary.
+func TestBackendParity(t *testing.T) {
+	for _, tt := range transcriptTests {
+		t.Run(tt.name, func(t *testing.T) {
+			poppler, _ := parseFixture(t, tt.name, pdf.PopplerToText)
+			pure, _ := parseFixture(t, tt.name, pdf.PureToText)
+			if !cmp.Equal(poppler, pure) {
+				diff := cmp.Diff(poppler, pure)
+				t.Fatalf("mismatch (-poppler +pure):\n%s", diff)
+			}
+			if !cmp.Equal(tt.want, pure) {
+				diff := cmp.Diff(tt.want, pure)
+				t.Fatalf("mismatch (-want +pure):\n%s", diff)
+			}
+		})
+	}
+}
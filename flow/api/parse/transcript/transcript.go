@@ -2,26 +2,49 @@ package transcript
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"flow/api/parse/diagnostic"
 	"flow/common/util"
 )
 
+type Course struct {
+	// Course codes are similar to CS 145, STAT 920, PD 1, CHINA 120R.
+	// They are lowercased and stripped of whitespace, e.g. cs145.
+	Code string
+	// Titles are as printed, e.g. Designing Functional Programs (Advanced Level).
+	// Titles that wrap onto the next line are truncated to the first line.
+	Title string
+	// Credits are multiples of 0.25 or so, e.g. 0.50 for a typical course.
+	// Both are zero for courses in progress.
+	AttemptedCredits float64
+	EarnedCredits    float64
+	// Grades are either numeric (e.g. 97) or not (e.g. CR, NG).
+	// The grade is empty for courses in progress.
+	Grade string
+	// Some courses, such as those annotated "Degree Requirement, Not in Average",
+	// do not count towards term and cumulative averages.
+	InAverage bool
+}
+
 type TermSummary struct {
 	// Term ids are numbers of the form 1189 (Fall 2018)
 	TermId int
 	// Levels are similar to 1A, 5C (delayed graduation).
-	Level string
-	// Course codes are similar to CS 145, STAT 920, PD 1, CHINA 120R.
-	Courses []string
+	Level   string
+	Courses []Course
 }
 
 type Summary struct {
 	StudentNumber int
 	ProgramName   string
 	TermSummaries []TermSummary
+	// Transfer (AP/IB) credits were not taken at UW, so they belong to no term.
+	// Only their code, title and earned credits are filled in.
+	TransferCredits []Course
 }
 
 var (
@@ -31,14 +54,28 @@ var (
 	// characters that are exactly 0x20? This whitespace must be column padding.
 	// This distinguishes courses in the taken table from courses in the notes
 	// (e.g. course equivalences established during program transfer)
-	courseRegexp = regexp.MustCompile(`([A-Z]{2,})\x20{2,}(\d{1,3}\w*)\x20{2,}.*\n`)
+	courseRegexp = regexp.MustCompile(`([A-Z]{2,})\x20{2,}(\d{1,3}\w*)\x20{2,}(.*)\n`)
 	creditRegexp = regexp.MustCompile(`\d.\d{2}`)
+	// The rest of a course line: title, then attempted and earned credits and grade, if any.
+	// Titles may contain runs of spaces themselves, so we match from the right.
+	courseRestRegexp = regexp.MustCompile(`^(.*?)(?:\s+(\d+\.\d{2})\s+(\d+\.\d{2})\s+(\S+))?\s*$`)
+	// Transfer credits only have earned credits, e.g. "MATH Transfer Credit   0.50".
+	transferRestRegexp = regexp.MustCompile(`^(.*?)\s+(\d+\.\d{2})\s*$`)
+	// This note is printed on the line following the course that it applies to.
+	notInAverageRegexp = regexp.MustCompile(`^[^\n]*Not in Average`)
 	// Level may contain more than two letters or numbers.
 	levelRegexp     = regexp.MustCompile(`Level:\s+(\w{2,})`)
 	studentIdRegexp = regexp.MustCompile(`Student ID:\s+(\d+)`)
 	// Term names may show up in transcript comments which should be ignored.
 	// We only want terms that show up on their own line.
 	termRegexp = regexp.MustCompile(`(?m)^\s*(Fall|Winter|Spring)\s+(\d{4})\s*$`)
+
+	// Lines that begin like courses but may not be padded like them.
+	// These are the lines that we expect courseRegexp to match,
+	// so we report those that it does not.
+	courseLikeRegexp = regexp.MustCompile(`(?m)^\s*[A-Z]{2,}\s+\d{1,3}[A-Z]*\s+\S.*$`)
+	// Each completed term ends with the credits attempted and earned in it.
+	termTotalsRegexp = regexp.MustCompile(`Term Totals\s+(\d+\.\d{2})\s+(\d+\.\d{2})`)
 )
 
 // courseLine is of one of the following forms:
@@ -54,12 +91,76 @@ func isTransferCredit(courseLine string) bool {
 	return len(matches) == 1
 }
 
-func extractTermSummaries(text string) ([]TermSummary, error) {
+// parseCourseRest parses what follows the course code on a course line.
+// The code itself is not filled in.
+func parseCourseRest(rest string) (*Course, error) {
+	submatches := courseRestRegexp.FindStringSubmatch(rest)
+	if submatches == nil {
+		return nil, fmt.Errorf("malformed course line: %q", rest)
+	}
+
+	// Title may contain column padding if it is very long
+	course := &Course{Title: strings.Join(strings.Fields(submatches[1]), " ")}
+	if submatches[2] == "" {
+		return course, nil
+	}
+
+	var err error
+	course.AttemptedCredits, err = strconv.ParseFloat(submatches[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("attempted credits not a number: %w", err)
+	}
+	course.EarnedCredits, err = strconv.ParseFloat(submatches[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("earned credits not a number: %w", err)
+	}
+	course.Grade = submatches[4]
+	return course, nil
+}
+
+// parseTransferRest is like parseCourseRest, but for transfer credit lines.
+func parseTransferRest(rest string) (*Course, error) {
+	submatches := transferRestRegexp.FindStringSubmatch(rest)
+	if submatches == nil {
+		return nil, fmt.Errorf("malformed transfer credit line: %q", rest)
+	}
+
+	course := &Course{Title: strings.Join(strings.Fields(submatches[1]), " ")}
+	var err error
+	course.EarnedCredits, err = strconv.ParseFloat(submatches[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("earned credits not a number: %w", err)
+	}
+	return course, nil
+}
+
+// extractTransferCredits finds transfer credits anywhere on the transcript.
+// They are listed in their own section, which is not tied to any term.
+func extractTransferCredits(text string) ([]Course, error) {
+	var credits []Course
+	for _, match := range courseRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if !isTransferCredit(text[match[0]:match[1]]) {
+			continue
+		}
+		department := text[match[2]:match[3]]
+		number := text[match[4]:match[5]]
+		course, err := parseTransferRest(text[match[6]:match[7]])
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", department, number, err)
+		}
+		course.Code = strings.ToLower(department + number)
+		credits = append(credits, *course)
+	}
+	return credits, nil
+}
+
+func extractTermSummaries(text string, report *diagnostic.Report) ([]TermSummary, error) {
 	// Passing -1 means setting no upper limit on number of matches
 	terms := termRegexp.FindAllStringSubmatchIndex(text, -1)
 	levels := levelRegexp.FindAllStringSubmatchIndex(text, -1)
 	courses := courseRegexp.FindAllStringSubmatchIndex(text, -1)
 	if len(terms) != len(levels) {
+		report.Mismatch("%d terms, but %d academic levels", len(terms), len(levels))
 		return nil, fmt.Errorf("some terms lack academic level")
 	}
 	history := make([]TermSummary, len(terms))
@@ -76,21 +177,69 @@ func extractTermSummaries(text string) ([]TermSummary, error) {
 		// except for the last term, which includes all remaining courses.
 		for ; j < len(courses) && (i == len(terms)-1 || courses[j][0] < terms[i+1][0]); j++ {
 			// Some courses are transfer (AP/IB) credits.
-			// They were not taken at UW, so should not be imported.
+			// They were not taken at UW, so are extracted separately.
 			if isTransferCredit(text[courses[j][0]:courses[j][1]]) {
 				continue
 			}
 			department := text[courses[j][2]:courses[j][3]]
 			number := text[courses[j][4]:courses[j][5]]
-			history[i].Courses = append(
-				history[i].Courses,
-				strings.ToLower(department+number),
-			)
+			course, err := parseCourseRest(text[courses[j][6]:courses[j][7]])
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", department, number, err)
+			}
+			course.Code = strings.ToLower(department + number)
+			course.InAverage = !notInAverageRegexp.MatchString(text[courses[j][1]:])
+			history[i].Courses = append(history[i].Courses, *course)
 		}
+
+		end := len(text)
+		if i+1 < len(terms) {
+			end = terms[i+1][0]
+		}
+		checkTermTotals(text[terms[i][1]:end], &history[i], report)
+		report.Matched += 1 + len(history[i].Courses)
 	}
 	return history, nil
 }
 
+// checkTermTotals compares the credits of the courses of a term
+// to the totals printed below them, if the term is complete.
+func checkTermTotals(termText string, term *TermSummary, report *diagnostic.Report) {
+	submatches := termTotalsRegexp.FindStringSubmatch(termText)
+	if submatches == nil {
+		return
+	}
+	attempted, _ := strconv.ParseFloat(submatches[1], 64)
+	earned, _ := strconv.ParseFloat(submatches[2], 64)
+
+	var sumAttempted, sumEarned float64
+	for _, course := range term.Courses {
+		sumAttempted += course.AttemptedCredits
+		sumEarned += course.EarnedCredits
+	}
+	// Credits have two decimal places, so anything closer is equal
+	if math.Abs(sumAttempted-attempted) > 0.005 || math.Abs(sumEarned-earned) > 0.005 {
+		report.Mismatch(
+			"term %d totals %.2f/%.2f credits, but its courses add up to %.2f/%.2f",
+			term.TermId, attempted, earned, sumAttempted, sumEarned,
+		)
+	}
+}
+
+// reportUnmatchedCourses reports lines that look like courses,
+// but are not matched by courseRegexp, e.g. because the padding changed.
+func reportUnmatchedCourses(text string, report *diagnostic.Report) {
+	for _, line := range courseLikeRegexp.FindAllString(text, -1) {
+		// Notes list course equivalences, e.g. "SE 101 + COOP1 = PD1"
+		if strings.Contains(line, "=") {
+			continue
+		}
+		if !courseRegexp.MatchString(line + "\n") {
+			report.Unmatched(strings.TrimSpace(line))
+		}
+	}
+}
+
 func extractProgramName(text string) (string, error) {
 	start := strings.LastIndex(text, "Program:")
 	if start == -1 {
@@ -106,7 +255,21 @@ func extractProgramName(text string) (string, error) {
 	return "", fmt.Errorf("unexpected end of transcript")
 }
 
-func Parse(text string) (*Summary, error) {
+// Parse extracts a summary from a transcript converted to text.
+// The report is returned even if parsing fails, as that is when it is most useful.
+func Parse(text string) (*Summary, *diagnostic.Report, error) {
+	report := &diagnostic.Report{}
+	summary, err := parse(text, report)
+	if err != nil {
+		report.Fail(err)
+		return nil, report, err
+	}
+	reportUnmatchedCourses(text, report)
+	report.Score()
+	return summary, report, nil
+}
+
+func parse(text string, report *diagnostic.Report) (*Summary, error) {
 	submatches := studentIdRegexp.FindStringSubmatchIndex(text)
 	if submatches == nil {
 		return nil, fmt.Errorf("student id not found")
@@ -121,15 +284,22 @@ func Parse(text string) (*Summary, error) {
 		return nil, fmt.Errorf("extracting program name: %w", err)
 	}
 
-	termSummaries, err := extractTermSummaries(text)
+	termSummaries, err := extractTermSummaries(text, report)
 	if err != nil {
 		return nil, fmt.Errorf("extracting term summaries: %w", err)
 	}
 
+	transferCredits, err := extractTransferCredits(text)
+	if err != nil {
+		return nil, fmt.Errorf("extracting transfer credits: %w", err)
+	}
+	report.Matched += len(transferCredits)
+
 	result := &Summary{
-		StudentNumber: studentNumber,
-		ProgramName:   programName,
-		TermSummaries: termSummaries,
+		StudentNumber:   studentNumber,
+		ProgramName:     programName,
+		TermSummaries:   termSummaries,
+		TransferCredits: transferCredits,
 	}
 	return result, nil
 }
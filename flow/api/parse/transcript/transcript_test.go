@@ -5,125 +5,312 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"flow/api/parse/diagnostic"
 	"flow/api/parse/pdf"
 
 	"github.com/google/go-cmp/cmp"
 )
 
-func TestParseTranscript(t *testing.T) {
-	tests := []struct {
-		name string
-		want *Summary
-	}{
-		{
-			"simple",
-			&Summary{
-				StudentNumber: 20705374,
-				ProgramName:   "Computer Science/Digital Hardware Option",
-				TermSummaries: []TermSummary{
-					{
-						TermId:  1179,
-						Level:   "1A",
-						Courses: []string{"cs145", "math145", "math147", "psych101", "spcom223"},
+// transcriptTests are named after the fixtures in testdata.
+var transcriptTests = []struct {
+	name string
+	want *Summary
+}{
+	{
+		"simple",
+		&Summary{
+			StudentNumber: 20705374,
+			ProgramName:   "Computer Science/Digital Hardware Option",
+			TermSummaries: []TermSummary{
+				{
+					TermId: 1179,
+					Level:  "1A",
+					Courses: []Course{
+						{"cs145", "Designing Functional Programs (Advanced Level)", 0.5, 0.5, "97", true},
+						{"math145", "Algebra (Advanced Level)", 0.5, 0.5, "95", true},
+						{"math147", "Calculus 1 (Advanced Level)", 0.5, 0.5, "97", true},
+						{"psych101", "Introductory Psychology", 0.5, 0.5, "84", true},
+						{"spcom223", "Public Speaking", 0.5, 0.5, "85", true},
 					},
-					{
-						TermId:  1181,
-						Level:   "1B",
-						Courses: []string{"cs146", "ece124", "engl306a", "math146", "math148", "pd1", "stat230"},
+				},
+				{
+					TermId: 1181,
+					Level:  "1B",
+					Courses: []Course{
+						{"cs146", "Elementary Algorithm Design and Data Abstraction", 0.5, 0.5, "100", true},
+						{"ece124", "Digital Circuits and Systems", 0.5, 0.5, "95", true},
+						{"engl306a", "Introduction to Linguistics", 0.5, 0.5, "93", true},
+						{"math146", "Linear Algebra 1 (Advanced level)", 0.5, 0.5, "100", true},
+						{"math148", "Calculus 2 (Advanced Level)", 0.5, 0.5, "92", true},
+						{"pd1", "Career Fundamentals", 0.5, 0.0, "CR", true},
+						{"stat230", "Probability", 0.5, 0.5, "90", true},
 					},
-					{
-						TermId:  1185,
-						Level:   "2A",
-						Courses: []string{"coop1", "pd11"},
+				},
+				{
+					TermId: 1185,
+					Level:  "2A",
+					Courses: []Course{
+						{"coop1", "Co-operative Work Term", 0.5, 0.0, "CR", true},
+						{"pd11", "Processes for Technical Report Writing", 0.5, 0.0, "CR", true},
 					},
-					{
-						TermId:  1189,
-						Level:   "2A",
-						Courses: []string{"cs241e", "cs245", "cs246e", "ece222", "math249"},
+				},
+				{
+					TermId: 1189,
+					Level:  "2A",
+					Courses: []Course{
+						{"cs241e", "Foundations of Sequential Programs (Enriched)", 0.5, 0.5, "100", true},
+						{"cs245", "Logic and Computation", 0.5, 0.5, "91", true},
+						{"cs246e", "Object-Oriented Software Development (Enriched)", 0.5, 0.5, "100", true},
+						{"ece222", "Digital Computers", 0.5, 0.5, "100", true},
+						{"math249", "Introduction to Combinatorics (Advanced Level)", 0.5, 0.5, "81", true},
 					},
-					{
-						TermId:  1191,
-						Level:   "2B",
-						Courses: []string{"coop2", "pd10", "wkrpt200m"},
+				},
+				{
+					TermId: 1191,
+					Level:  "2B",
+					Courses: []Course{
+						{"coop2", "Co-operative Work Term", 0.5, 0.0, "CR", true},
+						{"pd10", "Professional Responsibility in Computing", 0.5, 0.0, "CR", true},
+						{"wkrpt200m", "Work-term Report", 0.13, 0.0, "NG", true},
 					},
-					{
-						TermId:  1195,
-						Level:   "2B",
-						Courses: []string{"cs240e", "cs370", "math245", "math247", "stat231"},
+				},
+				{
+					TermId: 1195,
+					Level:  "2B",
+					Courses: []Course{
+						{"cs240e", "Data Structures and Data Management (Enriched)", 0, 0, "", true},
+						{"cs370", "Numerical Computation", 0, 0, "", true},
+						{"math245", "Linear Algebra 2 (Advanced Level)", 0, 0, "", true},
+						{"math247", "Calculus 3 (Advanced Level)", 0, 0, "", true},
+						{"stat231", "Statistics", 0, 0, "", true},
 					},
 				},
 			},
 		},
-		{
-			"transfer",
-			&Summary{
-				StudentNumber: 20718692,
-				ProgramName:   "Computer Science",
-				TermSummaries: []TermSummary{
-					{
-						TermId:  1179,
-						Level:   "1A",
-						Courses: []string{"cs137", "ece105", "math115", "math117", "math135", "se101"},
+	},
+	{
+		"transfer",
+		&Summary{
+			StudentNumber: 20718692,
+			ProgramName:   "Computer Science",
+			TermSummaries: []TermSummary{
+				{
+					TermId: 1179,
+					Level:  "1A",
+					Courses: []Course{
+						{"cs137", "Programming Principles", 0.5, 0.5, "86", true},
+						{"ece105", "Classical Mechanics", 0.5, 0.5, "75", true},
+						{"math115", "Linear Algebra for Engineering", 0.5, 0.5, "90", true},
+						{"math117", "Calculus 1 for Engineering", 0.5, 0.5, "93", true},
+						{"math135", "Algebra for Honours Mathematics", 0.5, 0.5, "87", true},
+						{"se101", "Introduction to Methods of Software Engineering", 0.25, 0.25, "98", true},
 					},
-					{
-						TermId:  1181,
-						Level:   "1B",
-						Courses: []string{"cs138", "ece106", "ece124", "ece140", "math119"},
+				},
+				{
+					TermId: 1181,
+					Level:  "1B",
+					Courses: []Course{
+						{"cs138", "Introduction to Data Abstraction and Implementation", 0.5, 0.5, "89", true},
+						{"ece106", "Electricity and Magnetism", 0.5, 0.5, "72", true},
+						{"ece124", "Digital Circuits and Systems", 0.5, 0.5, "84", true},
+						{"ece140", "Linear Circuits", 0.5, 0.5, "75", true},
+						{"math119", "Calculus 2 for Engineering", 0.5, 0.5, "87", true},
 					},
-					{
-						TermId:  1185,
-						Level:   "1B",
-						Courses: []string{"coop1", "pd20"},
+				},
+				{
+					TermId: 1185,
+					Level:  "1B",
+					Courses: []Course{
+						{"coop1", "Co-operative Work Term", 0.5, 0.0, "CR", true},
+						{"pd20", "Engineering Workplace Skills I: Developing Reasoned", 0.5, 0.5, "CR", true},
 					},
-					{
-						TermId:  1189,
-						Level:   "2A",
-						Courses: []string{"che102", "cs241e", "ece222", "se212", "smf213", "spcom223", "stat206"},
+				},
+				{
+					TermId: 1189,
+					Level:  "2A",
+					Courses: []Course{
+						{"che102", "Chemistry for Engineers", 0.5, 0.5, "84", true},
+						{"cs241e", "Foundations of Sequential Programs (Enriched)", 0.5, 0.5, "78", true},
+						{"ece222", "Digital Computers", 0.5, 0.5, "89", true},
+						{"se212", "Logic and Computation", 0.5, 0.5, "73", true},
+						{"smf213", "Sexual Health and Well-Being", 0.5, 0.5, "83", false},
+						{"spcom223", "Public Speaking", 0.5, 0.5, "85", true},
+						{"stat206", "Statistics for Software Engineering", 0.5, 0.5, "86", true},
 					},
-					{
-						TermId:  1191,
-						Level:   "2A",
-						Courses: []string{"coop2", "pd21"},
+				},
+				{
+					TermId: 1191,
+					Level:  "2A",
+					Courses: []Course{
+						{"coop2", "Co-operative Work Term", 0.5, 0.0, "CR", true},
+						{"pd21", "Engineering Workplace Skills II: Developing Effective", 0.5, 0.5, "CR", true},
 					},
-					{
-						TermId:  1195,
-						Level:   "2B",
-						Courses: []string{"cs240", "cs247", "earth121", "ece358", "math239", "msci261", "wkrpt200"},
+				},
+				{
+					TermId: 1195,
+					Level:  "2B",
+					Courses: []Course{
+						{"cs240", "Data Structures and Data Management", 0.5, 0.5, "85", true},
+						{"cs247", "Software Engineering Principles", 0.5, 0.5, "89", true},
+						{"earth121", "Introductory Earth Sciences", 0.5, 0.5, "83", false},
+						{"ece358", "Computer Networks", 0.5, 0.5, "80", true},
+						{"math239", "Introduction to Combinatorics", 0.5, 0.5, "74", true},
+						{"msci261", "Engineering Economics: Financial Management for", 0.5, 0.5, "87", true},
+						{"wkrpt200", "Work-term Report", 0.13, 0.13, "95", false},
 					},
-					{
-						TermId:  1199,
-						Level:   "2B",
-						Courses: []string{"coop3", "pd10"},
+				},
+				{
+					TermId: 1199,
+					Level:  "2B",
+					Courses: []Course{
+						{"coop3", "Co-operative Work Term", 0.5, 0.0, "CR", true},
+						{"pd10", "Professional Responsibility in Computing", 0.5, 0.5, "CR", true},
 					},
-					{
-						TermId:  1201,
-						Level:   "4A",
-						Courses: []string{"cs341", "cs350", "cs370", "phil256", "syde552"},
+				},
+				{
+					TermId: 1201,
+					Level:  "4A",
+					Courses: []Course{
+						{"cs341", "Algorithms", 0, 0, "", true},
+						{"cs350", "Operating Systems", 0, 0, "", true},
+						{"cs370", "Numerical Computation", 0, 0, "", true},
+						{"phil256", "Introduction to Cognitive Science", 0, 0, "", true},
+						{"syde552", "Computational Neuroscience", 0, 0, "", true},
 					},
 				},
 			},
+			TransferCredits: []Course{
+				{"chem123", "Chem Reac,Equilibria,Kinetics", 0, 0.5, "", false},
+				{"chem123l", "Chemical Reactions Lab 2", 0, 0.25, "", false},
+				{"math1xx", "MATH Transfer Credit", 0, 0.5, "", false},
+			},
 		},
+	},
+}
+
+// parseFixture converts the named fixture to text with toText and parses it.
+func parseFixture(t *testing.T, name string, toText func([]byte) (string, error)) (*Summary, *diagnostic.Report) {
+	path := fmt.Sprintf("testdata/transcript-%s.pdf", name)
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading pdf: %v", err)
 	}
+	text, err := toText(bytes)
+	if err != nil {
+		t.Fatalf("converting: %v", err)
+	}
+	got, report, err := Parse(text)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	return got, report
+}
 
-	for _, tt := range tests {
+func TestParseTranscript(t *testing.T) {
+	for _, tt := range transcriptTests {
 		t.Run(tt.name, func(t *testing.T) {
-			path := fmt.Sprintf("testdata/transcript-%s.pdf", tt.name)
-			bytes, err := ioutil.ReadFile(path)
-			if err != nil {
-				t.Fatalf("reading pdf: %v", err)
-			}
-			text, err := pdf.ToText(bytes)
-			if err != nil {
-				t.Fatalf("converting: %v", err)
-			}
-			got, err := Parse(text)
-			if err != nil {
-				t.Fatalf("parsing: %v", err)
-			}
+			got, report := parseFixture(t, tt.name, pdf.ToText)
 			if !cmp.Equal(tt.want, got) {
 				diff := cmp.Diff(tt.want, got)
 				t.Fatalf("mismatch (-want +got):\n%s", diff)
 			}
+			// Fixtures are exactly what the parser expects
+			if report.Confidence != 1 {
+				t.Errorf("Expected full confidence, but got %+v", report)
+			}
 		})
 	}
 }
+
+func TestParseCourseRest(t *testing.T) {
+	tests := []struct {
+		rest string
+		want *Course
+	}{
+		{
+			"Designing Functional Programs (Advanced Level)      0.50        0.50    97",
+			&Course{Title: "Designing Functional Programs (Advanced Level)", AttemptedCredits: 0.5, EarnedCredits: 0.5, Grade: "97"},
+		},
+		{
+			"Engineering Economics:  Financial Management for    0.50        0.50    87",
+			&Course{Title: "Engineering Economics: Financial Management for", AttemptedCredits: 0.5, EarnedCredits: 0.5, Grade: "87"},
+		},
+		{
+			"Work-term Report                                    0.13        0.00    NG",
+			&Course{Title: "Work-term Report", AttemptedCredits: 0.13, Grade: "NG"},
+		},
+		{
+			"Calculus 3 (Advanced Level)",
+			&Course{Title: "Calculus 3 (Advanced Level)"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCourseRest(tt.rest)
+		if err != nil {
+			t.Errorf("parseCourseRest(%q): unexpected error: %v", tt.rest, err)
+			continue
+		}
+		if !cmp.Equal(tt.want, got) {
+			t.Errorf("parseCourseRest(%q) mismatch (-want +got):\n%s", tt.rest, cmp.Diff(tt.want, got))
+		}
+	}
+}
+
+func TestParseTransferRest(t *testing.T) {
+	rest := "Chem Reac,Equilibria,Kinetics                                   0.50 "
+	want := &Course{Title: "Chem Reac,Equilibria,Kinetics", EarnedCredits: 0.5}
+
+	got, err := parseTransferRest(rest)
+	if err != nil {
+		t.Fatalf("parseTransferRest(%q): unexpected error: %v", rest, err)
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("parseTransferRest(%q) mismatch (-want +got):\n%s", rest, cmp.Diff(want, got))
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	// The second course is padded with single spaces, as if the layout changed,
+	// so it is dropped and the term totals no longer add up.
+	text := `
+  Student ID:   20705374
+                                                            Fall 2017
+ Program:   Computer Science, Honours, Co-operative Program
+   Level:           1A                Load: Full-Time
+  Course                      Description                                         Attempted       Earned    Grade
+  CS             145          Designing Functional Programs (Advanced Level)           0.50         0.50     97
+  MATH 145 Algebra (Advanced Level)      0.50         0.50     95
+                                                                                     In GPA       Earned
+  Term GPA                         96.00  Term Totals                                  1.00         1.00
+`
+	summary, report, err := Parse(text)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	if len(summary.TermSummaries) != 1 || len(summary.TermSummaries[0].Courses) != 1 {
+		t.Fatalf("Expected one term with one course, but got %+v", summary.TermSummaries)
+	}
+	wantUnmatched := []string{"MATH 145 Algebra (Advanced Level)      0.50         0.50     95"}
+	if !cmp.Equal(wantUnmatched, report.UnmatchedLines) {
+		t.Errorf("unmatched lines mismatch (-want +got):\n%s", cmp.Diff(wantUnmatched, report.UnmatchedLines))
+	}
+	wantMismatches := []string{"term 1179 totals 1.00/1.00 credits, but its courses add up to 0.50/0.50"}
+	if !cmp.Equal(wantMismatches, report.Mismatches) {
+		t.Errorf("mismatches mismatch (-want +got):\n%s", cmp.Diff(wantMismatches, report.Mismatches))
+	}
+	// Two of three items were matched, with a penalty for the mismatch
+	if report.Confidence != 0.5 {
+		t.Errorf("Expected confidence 0.5, but got %v", report.Confidence)
+	}
+}
+
+func TestParseDiagnosticsFailure(t *testing.T) {
+	_, report, err := Parse("Student ID:   20705374\n  Program: Mathematics\nFall 2017\n")
+	if err == nil {
+		t.Fatalf("Expected an error for a term without academic level")
+	}
+	if report == nil || report.Confidence != 0 || len(report.Mismatches) == 0 {
+		t.Errorf("Expected a report with zero confidence, but got %+v", report)
+	}
+}
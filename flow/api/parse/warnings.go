@@ -0,0 +1,177 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"flow/api/parse/schedule"
+	"flow/common/db"
+)
+
+// scheduleWarning describes a difference between a pasted schedule
+// and the sections imported from the UW API, such as a room change.
+// The schedule is still imported: the warning only lets the user know
+// that one of the two is out of date, which is usually the imported data.
+type scheduleWarning struct {
+	ClassNumber int `json:"class_number"`
+	// Field is what differs: "section", "time" or "location"
+	Field    string `json:"field"`
+	Imported string `json:"imported"`
+	Pasted   string `json:"pasted"`
+}
+
+type importedMeeting struct {
+	Location     *string
+	StartSeconds *int
+	EndSeconds   *int
+	Days         []string
+}
+
+type importedSection struct {
+	SectionName string
+	Meetings    []importedMeeting
+}
+
+const selectMeetingsQuery = `
+SELECT cs.class_number, cs.section_name, sm.location, sm.start_seconds, sm.end_seconds, sm.days
+FROM course_section cs
+  JOIN section_meeting sm ON sm.section_id = cs.id
+WHERE cs.term_id = $1 AND cs.class_number = ANY($2) AND NOT sm.is_cancelled
+`
+
+func scheduleWarnings(tx *db.Tx, summary *schedule.Summary) ([]scheduleWarning, error) {
+	classNumbers := make([]int, len(summary.Classes))
+	for i, class := range summary.Classes {
+		classNumbers[i] = class.Number
+	}
+
+	rows, err := tx.Query(selectMeetingsQuery, summary.TermId, classNumbers)
+	if err != nil {
+		return nil, fmt.Errorf("querying section meetings: %w", err)
+	}
+	defer rows.Close()
+
+	sections := make(map[int]*importedSection)
+	for rows.Next() {
+		var classNumber int
+		var sectionName string
+		var meeting importedMeeting
+		err = rows.Scan(
+			&classNumber, &sectionName, &meeting.Location,
+			&meeting.StartSeconds, &meeting.EndSeconds, &meeting.Days,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("reading section meeting: %w", err)
+		}
+		section, ok := sections[classNumber]
+		if !ok {
+			section = &importedSection{SectionName: sectionName}
+			sections[classNumber] = section
+		}
+		section.Meetings = append(section.Meetings, meeting)
+	}
+
+	var warnings []scheduleWarning
+	for _, class := range summary.Classes {
+		// Classes without a section are reported as failed instead
+		if section, ok := sections[class.Number]; ok {
+			warnings = append(warnings, classWarnings(class, section)...)
+		}
+	}
+	return warnings, nil
+}
+
+// normalizeLocation removes the extra spacing that older schedules have, e.g. "MC   4040"
+func normalizeLocation(location string) string {
+	return strings.Join(strings.Fields(location), " ")
+}
+
+// formatMeetingTime formats days and times like Quest does, e.g. "TTh 2:30PM - 3:50PM"
+func formatMeetingTime(days []string, startSeconds, endSeconds int) string {
+	var midnight time.Time
+	return fmt.Sprintf(
+		"%s %s - %s",
+		strings.Join(days, ""),
+		midnight.Add(time.Duration(startSeconds)*time.Second).Format("3:04PM"),
+		midnight.Add(time.Duration(endSeconds)*time.Second).Format("3:04PM"),
+	)
+}
+
+func sameTime(pasted schedule.Meeting, imported importedMeeting) bool {
+	return imported.StartSeconds != nil && imported.EndSeconds != nil &&
+		*pasted.StartSeconds == *imported.StartSeconds &&
+		*pasted.EndSeconds == *imported.EndSeconds &&
+		strings.Join(pasted.Days, "") == strings.Join(imported.Days, "")
+}
+
+// classWarnings compares the pasted details of a class with its imported section.
+// Each meeting is matched to imported meetings at the same time,
+// and only then are their locations compared.
+// Details that are missing from either side, such as TBA times, are not compared.
+func classWarnings(class schedule.Class, section *importedSection) []scheduleWarning {
+	var warnings []scheduleWarning
+	seen := make(map[scheduleWarning]bool)
+	warn := func(field, imported, pasted string) {
+		warning := scheduleWarning{ClassNumber: class.Number, Field: field, Imported: imported, Pasted: pasted}
+		if !seen[warning] {
+			seen[warning] = true
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if class.Component != "" && class.Section != "" {
+		sectionName := class.Component + " " + class.Section
+		if sectionName != section.SectionName {
+			warn("section", section.SectionName, sectionName)
+		}
+	}
+
+	var importedTimes []string
+	for _, meeting := range section.Meetings {
+		if meeting.StartSeconds != nil && meeting.EndSeconds != nil {
+			importedTimes = append(importedTimes, formatMeetingTime(meeting.Days, *meeting.StartSeconds, *meeting.EndSeconds))
+		}
+	}
+
+	for _, pasted := range class.Meetings {
+		if pasted.StartSeconds == nil || pasted.EndSeconds == nil {
+			continue
+		}
+		var matches []importedMeeting
+		for _, imported := range section.Meetings {
+			if sameTime(pasted, imported) {
+				matches = append(matches, imported)
+			}
+		}
+		if len(matches) == 0 {
+			warn(
+				"time",
+				strings.Join(importedTimes, ", "),
+				formatMeetingTime(pasted.Days, *pasted.StartSeconds, *pasted.EndSeconds),
+			)
+			continue
+		}
+
+		location := normalizeLocation(pasted.Location)
+		if location == "" || location == "TBA" {
+			continue
+		}
+		var importedLocation string
+		matched := false
+		for _, imported := range matches {
+			if imported.Location == nil {
+				continue
+			}
+			if normalizeLocation(*imported.Location) == location {
+				matched = true
+				break
+			}
+			importedLocation = normalizeLocation(*imported.Location)
+		}
+		if !matched && importedLocation != "" {
+			warn("location", importedLocation, location)
+		}
+	}
+	return warnings
+}
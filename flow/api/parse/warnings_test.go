@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"flow/api/parse/schedule"
+	"flow/common/util"
+)
+
+func TestClassWarnings(t *testing.T) {
+	lecture := schedule.Meeting{
+		Days:         []string{"T", "Th"},
+		StartSeconds: util.IntToPointer(52200), EndSeconds: util.IntToPointer(57000),
+		Location: "MC   2038",
+	}
+	section := &importedSection{
+		SectionName: "LEC 001",
+		Meetings: []importedMeeting{{
+			Days:         []string{"T", "Th"},
+			StartSeconds: util.IntToPointer(52200), EndSeconds: util.IntToPointer(57000),
+			Location: util.StringToPointer("MC 2038"),
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		class   schedule.Class
+		section *importedSection
+		want    []scheduleWarning
+	}{
+		{
+			"unchanged",
+			schedule.Class{Number: 4896, Section: "001", Component: "LEC", Meetings: []schedule.Meeting{lecture}},
+			section,
+			nil,
+		},
+		{
+			"room change",
+			schedule.Class{
+				Number: 4896, Section: "001", Component: "LEC",
+				Meetings: []schedule.Meeting{{
+					Days: lecture.Days, StartSeconds: lecture.StartSeconds, EndSeconds: lecture.EndSeconds,
+					Location: "E7 4053",
+				}},
+			},
+			section,
+			[]scheduleWarning{{4896, "location", "MC 2038", "E7 4053"}},
+		},
+		{
+			"time change",
+			schedule.Class{
+				Number: 4896, Section: "001", Component: "LEC",
+				Meetings: []schedule.Meeting{{
+					Days:         []string{"M", "W"},
+					StartSeconds: util.IntToPointer(30600), EndSeconds: util.IntToPointer(35400),
+					Location: "MC 2038",
+				}},
+			},
+			section,
+			[]scheduleWarning{{4896, "time", "TTh 2:30PM - 3:50PM", "MW 8:30AM - 9:50AM"}},
+		},
+		{
+			"section mismatch",
+			schedule.Class{Number: 4896, Section: "002", Component: "LEC", Meetings: []schedule.Meeting{lecture}},
+			section,
+			[]scheduleWarning{{4896, "section", "LEC 001", "LEC 002"}},
+		},
+		{
+			"missing details",
+			schedule.Class{
+				Number: 4896,
+				Meetings: []schedule.Meeting{
+					{Location: "TBA"},
+					{Days: lecture.Days, StartSeconds: lecture.StartSeconds, EndSeconds: lecture.EndSeconds, Location: "TBA"},
+				},
+			},
+			section,
+			nil,
+		},
+		{
+			"repeated meetings",
+			schedule.Class{Number: 4896, Meetings: []schedule.Meeting{lecture, lecture}},
+			&importedSection{
+				SectionName: "LEC 001",
+				Meetings: []importedMeeting{
+					{Days: []string{"T", "Th"}, StartSeconds: util.IntToPointer(52200), EndSeconds: util.IntToPointer(57000)},
+					{Days: []string{"T", "Th"}, StartSeconds: util.IntToPointer(52200), EndSeconds: util.IntToPointer(57000),
+						Location: util.StringToPointer("DC 1350")},
+				},
+			},
+			[]scheduleWarning{{4896, "location", "DC 1350", "MC 2038"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classWarnings(tt.class, tt.section)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
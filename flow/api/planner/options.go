@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"flow/api/serde"
+	"flow/common/util"
+)
+
+type ranking int
+
+const (
+	// Schedules with more weekdays without classes come first
+	rankDaysOff ranking = iota
+	// Schedules whose earliest class of the week starts latest come first
+	rankLateStart
+	// Schedules whose fullest section has the most open seats come first
+	rankOpenSeats
+)
+
+var rankings = map[string]ranking{
+	"days_off":   rankDaysOff,
+	"late_start": rankLateStart,
+	"open_seats": rankOpenSeats,
+}
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+	// Each course multiplies the number of combinations, so there has to be a limit
+	maxCourses = 8
+)
+
+// plannerOptions are given as query parameters, e.g.
+// ?term=1249&courses=cs341,cs350&rank=late_start&limit=5
+type plannerOptions struct {
+	TermId int
+	// Course codes in lowercase, e.g. cs341
+	Courses []string
+	Rank    ranking
+	// Number of schedules to return
+	Limit int
+}
+
+func invalidOption(format string, args ...interface{}) error {
+	return serde.WithStatus(
+		http.StatusBadRequest,
+		serde.WithEnum(serde.InvalidPlannerOption, fmt.Errorf(format, args...)),
+	)
+}
+
+func parseOptions(query url.Values) (*plannerOptions, error) {
+	options := plannerOptions{Rank: rankDaysOff, Limit: defaultLimit}
+
+	value := query.Get("term")
+	termId, err := strconv.Atoi(value)
+	if err != nil || !util.IsValidTermId(termId) {
+		return nil, invalidOption("invalid term id: %q", value)
+	}
+	options.TermId = termId
+
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(query.Get("courses"), ",") {
+		// Codes are stored in lowercase without spaces, e.g. cs135
+		code = strings.ToLower(strings.ReplaceAll(code, " ", ""))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		options.Courses = append(options.Courses, code)
+	}
+	if len(options.Courses) == 0 || len(options.Courses) > maxCourses {
+		return nil, invalidOption("between 1 and %d courses are required, got %d", maxCourses, len(options.Courses))
+	}
+
+	if value := query.Get("rank"); value != "" {
+		rank, ok := rankings[value]
+		if !ok {
+			return nil, invalidOption("unknown ranking: %q", value)
+		}
+		options.Rank = rank
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return nil, invalidOption("limit must be between 1 and %d: %q", maxLimit, value)
+		}
+		options.Limit = limit
+	}
+
+	return &options, nil
+}
@@ -0,0 +1,193 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"flow/api/serde"
+	"flow/common/db"
+)
+
+// Enough for several courses with dozens of sections each,
+// while keeping the search well inside the request timeout.
+const maxSteps = 1000000
+
+// The search has its own deadline, well before the request times out after 10 seconds,
+// as a cancelled request could no longer commit its transaction to respond.
+const searchTimeout = 5 * time.Second
+
+type plannedSection struct {
+	CourseCode  string `json:"course_code"`
+	SectionName string `json:"section_name"`
+	ClassNumber int    `json:"class_number"`
+	OpenSeats   int    `json:"open_seats"`
+	// Sections of the same component meeting at the same times, which could be taken instead
+	AlternativeClassNumbers []int `json:"alternative_class_numbers"`
+}
+
+type plannedSchedule struct {
+	Sections []plannedSection `json:"sections"`
+	DaysOff  int              `json:"days_off"`
+	// Start of the earliest class of the week, or nil if no class has a time
+	EarliestStartSeconds *int `json:"earliest_start_seconds"`
+	// Open seats in the fullest section
+	OpenSeats int `json:"open_seats"`
+}
+
+type plannerResponse struct {
+	Schedules []plannedSchedule `json:"schedules"`
+	// Whether the search stopped early, in which case better schedules may exist
+	Truncated bool `json:"truncated"`
+}
+
+// Cancelled meetings do not take place, and those without times cannot conflict.
+// Sections without meetings are still returned, with NULL meeting columns.
+const selectSectionsQuery = `
+SELECT
+  c.code, cs.class_number, cs.section_name,
+  cs.enrollment_capacity - cs.enrollment_total,
+  sm.start_seconds, sm.end_seconds, sm.start_date :: TEXT, sm.end_date :: TEXT, sm.days
+FROM
+  course_section cs
+  JOIN course c ON c.id = cs.course_id
+  LEFT JOIN section_meeting sm ON sm.section_id = cs.id
+    AND NOT sm.is_cancelled
+    AND sm.start_seconds IS NOT NULL
+    AND sm.end_seconds IS NOT NULL
+WHERE cs.term_id = $1 AND c.code = ANY($2)
+ORDER BY c.code, cs.class_number
+`
+
+func selectSections(tx *db.Tx, options *plannerOptions) ([]*section, error) {
+	rows, err := tx.Query(selectSectionsQuery, options.TermId, options.Courses)
+	if err != nil {
+		return nil, fmt.Errorf("querying sections: %w", err)
+	}
+	defer rows.Close()
+
+	var sections []*section
+	for rows.Next() {
+		var s section
+		var startSeconds, endSeconds *int
+		var startDate, endDate *string
+		var days []string
+		err = rows.Scan(
+			&s.CourseCode, &s.ClassNumber, &s.SectionName, &s.OpenSeats,
+			&startSeconds, &endSeconds, &startDate, &endDate, &days,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("reading section: %w", err)
+		}
+
+		// Rows are ordered by class number, so meetings of a section are adjacent
+		last := len(sections) - 1
+		if last < 0 || sections[last].CourseCode != s.CourseCode || sections[last].ClassNumber != s.ClassNumber {
+			sections = append(sections, &s)
+			last++
+		}
+		if startSeconds != nil && startDate != nil && endDate != nil {
+			sections[last].Meetings = append(sections[last].Meetings, meeting{
+				StartSeconds: *startSeconds,
+				EndSeconds:   *endSeconds,
+				StartDate:    *startDate,
+				EndDate:      *endDate,
+				Days:         days,
+			})
+		}
+	}
+	return sections, nil
+}
+
+// checkCourses makes sure that every course has sections in the term,
+// as otherwise it would silently be left out of all schedules.
+func checkCourses(sections []*section, options *plannerOptions) error {
+	offered := make(map[string]bool)
+	for _, s := range sections {
+		offered[s.CourseCode] = true
+	}
+	var missing []string
+	for _, code := range options.Courses {
+		if !offered[code] {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		return serde.WithStatus(
+			http.StatusNotFound,
+			serde.WithEnum(serde.NoSections, fmt.Errorf("no sections in %d for %s", options.TermId, strings.Join(missing, ", "))),
+		)
+	}
+	return nil
+}
+
+func toSchedule(p *plan) plannedSchedule {
+	schedule := plannedSchedule{
+		Sections:  make([]plannedSection, 0, len(p.Options)),
+		DaysOff:   p.DaysOff,
+		OpenSeats: p.OpenSeats,
+	}
+	if p.Start != noStartSeconds {
+		start := p.Start
+		schedule.EarliestStartSeconds = &start
+	}
+
+	for _, opt := range p.Options {
+		best := opt.Sections[0]
+		alternatives := make([]int, 0, len(opt.Sections)-1)
+		for _, s := range opt.Sections[1:] {
+			alternatives = append(alternatives, s.ClassNumber)
+		}
+		sort.Ints(alternatives)
+		schedule.Sections = append(schedule.Sections, plannedSection{
+			CourseCode:              best.CourseCode,
+			SectionName:             best.SectionName,
+			ClassNumber:             best.ClassNumber,
+			OpenSeats:               best.OpenSeats,
+			AlternativeClassNumbers: alternatives,
+		})
+	}
+	sort.Slice(schedule.Sections, func(i, j int) bool {
+		a, b := schedule.Sections[i], schedule.Sections[j]
+		if a.CourseCode != b.CourseCode {
+			return a.CourseCode < b.CourseCode
+		}
+		return a.SectionName < b.SectionName
+	})
+	return schedule
+}
+
+// HandlePlanner enumerates schedules of sections of the given courses without conflicts,
+// choosing one section of each component (lecture, tutorial, lab and so on) of each course.
+func HandlePlanner(tx *db.Tx, r *http.Request) (interface{}, error) {
+	options, err := parseOptions(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := selectSections(tx, options)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCourses(sections, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+	defer cancel()
+	searcher := newSearcher(ctx, buildSlots(sections), options.Rank, options.Limit, maxSteps)
+	searcher.search(0)
+
+	response := plannerResponse{
+		Schedules: make([]plannedSchedule, 0, len(searcher.best)),
+		Truncated: searcher.truncated,
+	}
+	for _, p := range searcher.best {
+		response.Schedules = append(response.Schedules, toSchedule(p))
+	}
+	return response, nil
+}
@@ -0,0 +1,269 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	termStart = "2024-09-04"
+	termEnd   = "2024-12-03"
+)
+
+// newSection creates a section meeting at the given hour on the given days for an hour and twenty minutes
+func newSection(code string, classNumber int, name string, openSeats int, hour int, days ...string) *section {
+	s := &section{CourseCode: code, ClassNumber: classNumber, SectionName: name, OpenSeats: openSeats}
+	if len(days) > 0 {
+		s.Meetings = []meeting{{
+			StartSeconds: hour * 3600,
+			EndSeconds:   hour*3600 + 80*60,
+			StartDate:    termStart,
+			EndDate:      termEnd,
+			Days:         days,
+		}}
+	}
+	return s
+}
+
+func TestOverlaps(t *testing.T) {
+	base := meeting{StartSeconds: 36000, EndSeconds: 40800, StartDate: termStart, EndDate: termEnd, Days: []string{"T", "Th"}}
+	tests := []struct {
+		name  string
+		other meeting
+		want  bool
+	}{
+		{"same", base, true},
+		{"adjacent", meeting{StartSeconds: 40800, EndSeconds: 45600, StartDate: termStart, EndDate: termEnd, Days: []string{"T"}}, false},
+		{"other_days", meeting{StartSeconds: 36000, EndSeconds: 40800, StartDate: termStart, EndDate: termEnd, Days: []string{"M", "W"}}, false},
+		{"other_dates", meeting{StartSeconds: 36000, EndSeconds: 40800, StartDate: "2025-01-06", EndDate: "2025-04-07", Days: []string{"T"}}, false},
+		{"partial", meeting{StartSeconds: 39600, EndSeconds: 43200, StartDate: termStart, EndDate: termEnd, Days: []string{"Th"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.overlaps(&tt.other); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSlots(t *testing.T) {
+	sections := []*section{
+		newSection("cs135", 1, "LEC 001", 10, 10, "T", "Th"),
+		newSection("cs135", 2, "LEC 002", 20, 13, "T", "Th"),
+		newSection("cs135", 3, "TUT 101", 5, 9, "F"),
+		newSection("cs135", 4, "TUT 102", 15, 9, "F"),
+		newSection("cs135", 5, "TST 201", 0, 19, "W"),
+	}
+
+	slots := buildSlots(sections)
+	if len(slots) != 2 {
+		t.Fatalf("got %d slots; want 2", len(slots))
+	}
+	// The tutorials meet at the same time, so they are one option
+	tutorials := slots[0]
+	if len(tutorials.Options) != 1 || len(tutorials.Options[0].Sections) != 2 {
+		t.Fatalf("expected tutorials to form one option of two sections")
+	}
+	if tutorials.Options[0].Sections[0].ClassNumber != 4 {
+		t.Errorf("expected the tutorial with more open seats first")
+	}
+	if len(slots[1].Options) != 2 {
+		t.Errorf("got %d lecture options; want 2", len(slots[1].Options))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	sections := []*section{
+		newSection("cs341", 1, "LEC 001", 30, 8, "M", "W"),
+		newSection("cs341", 2, "LEC 002", 5, 14, "T", "Th"),
+		newSection("cs350", 3, "LEC 001", 10, 14, "T", "Th"),
+		newSection("cs350", 4, "LEC 002", 40, 11, "M", "W"),
+		newSection("cs350", 5, "TUT 101", 25, 16, "F"),
+		newSection("cs350", 6, "TUT 102", 20, 16, "F"),
+		// Online sections have no meetings and fit anywhere
+		newSection("cs370", 7, "LEC 081", 100, 0),
+	}
+
+	tests := []struct {
+		name string
+		rank ranking
+		want []plannedSchedule
+	}{
+		{
+			"days_off",
+			rankDaysOff,
+			[]plannedSchedule{
+				{
+					Sections: []plannedSection{
+						{CourseCode: "cs341", SectionName: "LEC 001", ClassNumber: 1, OpenSeats: 30, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "LEC 002", ClassNumber: 4, OpenSeats: 40, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "TUT 101", ClassNumber: 5, OpenSeats: 25, AlternativeClassNumbers: []int{6}},
+						{CourseCode: "cs370", SectionName: "LEC 081", ClassNumber: 7, OpenSeats: 100, AlternativeClassNumbers: []int{}},
+					},
+					DaysOff:              2,
+					EarliestStartSeconds: intPointer(8 * 3600),
+					OpenSeats:            25,
+				},
+				{
+					Sections: []plannedSection{
+						{CourseCode: "cs341", SectionName: "LEC 002", ClassNumber: 2, OpenSeats: 5, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "LEC 002", ClassNumber: 4, OpenSeats: 40, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "TUT 101", ClassNumber: 5, OpenSeats: 25, AlternativeClassNumbers: []int{6}},
+						{CourseCode: "cs370", SectionName: "LEC 081", ClassNumber: 7, OpenSeats: 100, AlternativeClassNumbers: []int{}},
+					},
+					DaysOff:              0,
+					EarliestStartSeconds: intPointer(11 * 3600),
+					OpenSeats:            5,
+				},
+			},
+		},
+		{
+			"late_start",
+			rankLateStart,
+			[]plannedSchedule{
+				{
+					Sections: []plannedSection{
+						{CourseCode: "cs341", SectionName: "LEC 002", ClassNumber: 2, OpenSeats: 5, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "LEC 002", ClassNumber: 4, OpenSeats: 40, AlternativeClassNumbers: []int{}},
+						{CourseCode: "cs350", SectionName: "TUT 101", ClassNumber: 5, OpenSeats: 25, AlternativeClassNumbers: []int{6}},
+						{CourseCode: "cs370", SectionName: "LEC 081", ClassNumber: 7, OpenSeats: 100, AlternativeClassNumbers: []int{}},
+					},
+					DaysOff:              0,
+					EarliestStartSeconds: intPointer(11 * 3600),
+					OpenSeats:            5,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSearcher(context.Background(), buildSlots(sections), tt.rank, len(tt.want), maxSteps)
+			s.search(0)
+			if s.truncated {
+				t.Errorf("expected the search to finish")
+			}
+			got := make([]plannedSchedule, len(s.best))
+			for i, p := range s.best {
+				got[i] = toSchedule(p)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
+
+// largeCourses resembles first-year courses with a few lectures and dozens of tutorials each
+func largeCourses(count int) []*section {
+	var sections []*section
+	days := [][]string{{"M", "W"}, {"T", "Th"}, {"W", "F"}}
+	classNumber := 0
+	for c := 0; c < count; c++ {
+		code := fmt.Sprintf("math%d", 100+c)
+		for l := 0; l < 6; l++ {
+			classNumber++
+			sections = append(sections, newSection(code, classNumber, fmt.Sprintf("LEC %03d", l+1), l, 8+l, days[(c+l)%3]...))
+		}
+		for u := 0; u < 40; u++ {
+			classNumber++
+			sections = append(sections, newSection(code, classNumber, fmt.Sprintf("TUT %03d", 101+u), u, 8+u%10, days[u%3][u%2]))
+		}
+	}
+	return sections
+}
+
+func TestSearchLimits(t *testing.T) {
+	slots := buildSlots(largeCourses(5))
+
+	s := newSearcher(context.Background(), slots, rankDaysOff, 10, maxSteps)
+	s.search(0)
+	if s.truncated || len(s.best) != 10 {
+		t.Errorf("expected the search to finish with 10 schedules, but kept %d (truncated: %v)", len(s.best), s.truncated)
+	}
+
+	s = newSearcher(context.Background(), slots, rankDaysOff, 10, 100)
+	s.search(0)
+	if !s.truncated || s.steps > 101 {
+		t.Errorf("expected the search to stop after 100 steps, but took %d (truncated: %v)", s.steps, s.truncated)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = newSearcher(ctx, slots, rankDaysOff, 10, maxSteps)
+	s.search(0)
+	if !s.truncated || s.steps > 1 {
+		t.Errorf("expected the search to stop once cancelled, but took %d steps", s.steps)
+	}
+}
+
+// deadlineContext passes its deadline once Err has been called a given number of times,
+// which stops a search after a known number of steps
+type deadlineContext struct {
+	context.Context
+	checks int
+}
+
+func (c *deadlineContext) Err() error {
+	c.checks--
+	if c.checks < 0 {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestSearchDeadline(t *testing.T) {
+	// The full search takes several thousand steps
+	slots := buildSlots(largeCourses(6))
+
+	// The deadline passes at the third check, long before maxSteps
+	ctx := &deadlineContext{Context: context.Background(), checks: 2}
+	s := newSearcher(ctx, slots, rankOpenSeats, maxLimit, maxSteps)
+	s.search(0)
+	if !s.truncated || s.steps != 2*checkInterval+1 {
+		t.Errorf("expected the search to stop at the deadline, but took %d steps (truncated: %v)", s.steps, s.truncated)
+	}
+	if len(s.best) == 0 {
+		t.Errorf("expected the schedules found before the deadline to be kept")
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	options, err := parseOptions(url.Values{
+		"term":    {"1249"},
+		"courses": {"CS 341,cs350,cs341"},
+		"rank":    {"late_start"},
+		"limit":   {"5"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &plannerOptions{TermId: 1249, Courses: []string{"cs341", "cs350"}, Rank: rankLateStart, Limit: 5}
+	if !cmp.Equal(want, options) {
+		t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, options))
+	}
+
+	invalid := []url.Values{
+		{"courses": {"cs341"}},
+		{"term": {"1248"}, "courses": {"cs341"}},
+		{"term": {"1249"}},
+		{"term": {"1249"}, "courses": {"a,b,c,d,e,f,g,h,i"}},
+		{"term": {"1249"}, "courses": {"cs341"}, "rank": {"fewest_days"}},
+		{"term": {"1249"}, "courses": {"cs341"}, "limit": {"0"}},
+	}
+	for _, query := range invalid {
+		if _, err := parseOptions(query); err == nil {
+			t.Errorf("expected an error for %v", query)
+		}
+	}
+}
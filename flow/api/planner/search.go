@@ -0,0 +1,323 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type meeting struct {
+	StartSeconds int
+	EndSeconds   int
+	// Dates are formatted as YYYY-MM-DD, so they compare as strings
+	StartDate string
+	EndDate   string
+	// Days like ["T", "Th"]
+	Days []string
+}
+
+func (m *meeting) overlaps(other *meeting) bool {
+	if m.StartSeconds >= other.EndSeconds || other.StartSeconds >= m.EndSeconds {
+		return false
+	}
+	if m.StartDate > other.EndDate || other.StartDate > m.EndDate {
+		return false
+	}
+	for _, day := range m.Days {
+		for _, otherDay := range other.Days {
+			if day == otherDay {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type section struct {
+	CourseCode  string
+	ClassNumber int
+	SectionName string
+	OpenSeats   int
+	// Meetings with known times. Sections without any, such as online ones, conflict with nothing.
+	Meetings []meeting
+}
+
+func (s *section) component() string {
+	component, _, _ := strings.Cut(s.SectionName, " ")
+	return component
+}
+
+// option is a set of interchangeable sections: of the same component of a course
+// and meeting at the same times. Only one of them needs to be searched,
+// which keeps large courses with dozens of tutorials tractable.
+type option struct {
+	// Ordered by open seats, so the first one is suggested
+	Sections []*section
+	// Index into the conflict matrix of the search
+	index int
+}
+
+// slot is a component of a course, e.g. the tutorial of CS135,
+// for which one of the options has to be chosen
+type slot struct {
+	Options []*option
+}
+
+// meetingKey identifies the times at which the section meets
+func meetingKey(s *section) string {
+	keys := make([]string, len(s.Meetings))
+	for i, m := range s.Meetings {
+		keys[i] = fmt.Sprintf("%s %d-%d %s-%s", strings.Join(m.Days, ""), m.StartSeconds, m.EndSeconds, m.StartDate, m.EndDate)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ";")
+}
+
+// Tests are scheduled for the whole course, so there is no section to choose
+const testComponent = "TST"
+
+// buildSlots groups sections into slots and their options.
+// Slots with fewer options come first, so that conflicts are found early.
+func buildSlots(sections []*section) []*slot {
+	slotsByKey := make(map[string]*slot)
+	optionsByKey := make(map[string]*option)
+	var slots []*slot
+	for _, s := range sections {
+		component := s.component()
+		if component == testComponent {
+			continue
+		}
+
+		slotKey := s.CourseCode + " " + component
+		sl, ok := slotsByKey[slotKey]
+		if !ok {
+			sl = &slot{}
+			slotsByKey[slotKey] = sl
+			slots = append(slots, sl)
+		}
+
+		optionKey := slotKey + " " + meetingKey(s)
+		opt, ok := optionsByKey[optionKey]
+		if !ok {
+			opt = &option{}
+			optionsByKey[optionKey] = opt
+			sl.Options = append(sl.Options, opt)
+		}
+		opt.Sections = append(opt.Sections, s)
+	}
+
+	index := 0
+	for _, sl := range slots {
+		for _, opt := range sl.Options {
+			sort.SliceStable(opt.Sections, func(i, j int) bool {
+				return opt.Sections[i].OpenSeats > opt.Sections[j].OpenSeats
+			})
+			opt.index = index
+			index++
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		return len(slots[i].Options) < len(slots[j].Options)
+	})
+	return slots
+}
+
+// Days on which one could have a day off
+var weekdays = []string{"M", "T", "W", "Th", "F"}
+
+// Meetings never start this late, so it stands for schedules without timed meetings
+const noStartSeconds = 24 * 60 * 60
+
+type plan struct {
+	Options   []*option
+	DaysOff   int
+	Start     int
+	OpenSeats int
+}
+
+func newPlan(options []*option) *plan {
+	p := plan{
+		Options: append([]*option(nil), options...),
+		Start:   noStartSeconds,
+	}
+
+	busy := make(map[string]bool)
+	for i, opt := range options {
+		best := opt.Sections[0]
+		if i == 0 || best.OpenSeats < p.OpenSeats {
+			p.OpenSeats = best.OpenSeats
+		}
+		for _, m := range best.Meetings {
+			for _, day := range m.Days {
+				busy[day] = true
+			}
+			if m.StartSeconds < p.Start {
+				p.Start = m.StartSeconds
+			}
+		}
+	}
+	for _, day := range weekdays {
+		if !busy[day] {
+			p.DaysOff++
+		}
+	}
+	return &p
+}
+
+// better reports whether a ranks before b. Ties are broken by the other criteria.
+func better(rank ranking, a, b *plan) bool {
+	var criteria [3]int
+	switch rank {
+	case rankDaysOff:
+		criteria = [3]int{a.DaysOff - b.DaysOff, a.Start - b.Start, a.OpenSeats - b.OpenSeats}
+	case rankLateStart:
+		criteria = [3]int{a.Start - b.Start, a.DaysOff - b.DaysOff, a.OpenSeats - b.OpenSeats}
+	case rankOpenSeats:
+		criteria = [3]int{a.OpenSeats - b.OpenSeats, a.DaysOff - b.DaysOff, a.Start - b.Start}
+	}
+	for _, diff := range criteria {
+		if diff != 0 {
+			return diff > 0
+		}
+	}
+	return false
+}
+
+// How often the search checks whether its deadline passed, starting with the first step
+const checkInterval = 1024
+
+// searcher finds the best conflict-free choices of options by branch and bound.
+// The search stops after maxSteps options were tried, or when ctx is done,
+// in which case the best plans found so far are kept.
+type searcher struct {
+	ctx       context.Context
+	slots     []*slot
+	conflicts [][]bool
+	rank      ranking
+	limit     int
+	maxSteps  int
+
+	chosen    []*option
+	steps     int
+	truncated bool
+	// The best plans so far, ordered by rank
+	best []*plan
+}
+
+func newSearcher(ctx context.Context, slots []*slot, rank ranking, limit, maxSteps int) *searcher {
+	var options []*option
+	for _, sl := range slots {
+		options = append(options, sl.Options...)
+	}
+	conflicts := make([][]bool, len(options))
+	for _, opt := range options {
+		conflicts[opt.index] = make([]bool, len(options))
+	}
+	for i, a := range options {
+		for _, b := range options[i+1:] {
+			if optionsOverlap(a, b) {
+				conflicts[a.index][b.index] = true
+				conflicts[b.index][a.index] = true
+			}
+		}
+	}
+
+	return &searcher{
+		ctx:       ctx,
+		slots:     slots,
+		conflicts: conflicts,
+		rank:      rank,
+		limit:     limit,
+		maxSteps:  maxSteps,
+	}
+}
+
+func optionsOverlap(a, b *option) bool {
+	for i := range a.Sections[0].Meetings {
+		for j := range b.Sections[0].Meetings {
+			if a.Sections[0].Meetings[i].overlaps(&b.Sections[0].Meetings[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *searcher) fits(opt *option) bool {
+	for _, chosen := range s.chosen {
+		if s.conflicts[opt.index][chosen.index] {
+			return false
+		}
+	}
+	return true
+}
+
+// viable reports whether every slot from the given one on still has an option that fits.
+// Checking this before going deeper prunes branches that are bound to fail.
+func (s *searcher) viable(from int) bool {
+	for _, sl := range s.slots[from:] {
+		found := false
+		for _, opt := range sl.Options {
+			if s.fits(opt) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *searcher) record() {
+	p := newPlan(s.chosen)
+	i := sort.Search(len(s.best), func(i int) bool {
+		return better(s.rank, p, s.best[i])
+	})
+	if i >= s.limit {
+		return
+	}
+	s.best = append(s.best, nil)
+	copy(s.best[i+1:], s.best[i:])
+	s.best[i] = p
+	if len(s.best) > s.limit {
+		s.best = s.best[:s.limit]
+	}
+}
+
+// promising reports whether completing the chosen options could lead to a plan
+// that is better than the worst one kept. Adding sections never gives more days off,
+// a later start or more open seats, so the partial plan bounds all its completions.
+func (s *searcher) promising() bool {
+	if len(s.best) < s.limit {
+		return true
+	}
+	return better(s.rank, newPlan(s.chosen), s.best[len(s.best)-1])
+}
+
+func (s *searcher) search(depth int) {
+	if depth == len(s.slots) {
+		s.record()
+		return
+	}
+	for _, opt := range s.slots[depth].Options {
+		if s.truncated {
+			return
+		}
+		s.steps++
+		if s.steps > s.maxSteps || (s.steps%checkInterval == 1 && s.ctx.Err() != nil) {
+			s.truncated = true
+			return
+		}
+		if !s.fits(opt) {
+			continue
+		}
+		s.chosen = append(s.chosen, opt)
+		if s.viable(depth+1) && s.promising() {
+			s.search(depth + 1)
+		}
+		s.chosen = s.chosen[:len(s.chosen)-1]
+	}
+}
@@ -41,6 +41,30 @@ const (
 	//// Transcript import
 	// Transcript contains no terms
 	EmptyTranscript = "empty_transcript"
+	// Transcript file is larger than the upload limit
+	TranscriptTooLarge = "transcript_too_large"
+	// Transcript conversion took too long or used too many resources
+	TranscriptConversionFailed = "transcript_conversion_failed"
+
+	//// Calendar feed
+	// Query parameters of the feed URL are malformed or out of range
+	InvalidCalendarOption = "invalid_calendar_option"
+
+	//// Search
+	// Query parameters of the search are malformed or out of range
+	InvalidSearchOption = "invalid_search_option"
+
+	//// Timetable planner
+	// Query parameters of the planner are malformed or out of range
+	InvalidPlannerOption = "invalid_planner_option"
+	// Some of the requested courses have no sections in the term
+	NoSections = "no_sections"
+
+	//// Degree audit
+	// User has no program, as no transcript was imported
+	NoProgram = "no_program"
+	// There are no requirement definitions for the program of the user
+	UnknownProgram = "unknown_program"
 
 	//// Fallbacks
 	// These do not map exactly to 400 and 500 status codes respectively:
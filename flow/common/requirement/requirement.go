@@ -0,0 +1,99 @@
+// Boolean trees of course requirements
+package requirement
+
+import "strings"
+
+const (
+	AndOp    = "and"
+	OrOp     = "or"
+	CourseOp = "course"
+)
+
+// Tree is a requirement on the courses a student has taken.
+// It is stored as JSON on the course, so its shape must stay compatible.
+type Tree struct {
+	// Op is AndOp or OrOp for inner nodes and CourseOp for leaves.
+	Op string `json:"op"`
+	// Code is the code of the course of a leaf, e.g. cs135.
+	Code string `json:"code,omitempty"`
+	// Children of an inner node, of which all (AndOp) or one (OrOp) must hold.
+	Children []*Tree `json:"children,omitempty"`
+}
+
+func Course(code string) *Tree {
+	return &Tree{Op: CourseOp, Code: code}
+}
+
+// combine builds an inner node, leaving out nil children.
+// A node with a single child is replaced by it, and one without any is nil.
+func combine(op string, children []*Tree) *Tree {
+	var kept []*Tree
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		// (a and b) and c is a and b and c
+		if child.Op == op {
+			kept = append(kept, child.Children...)
+		} else {
+			kept = append(kept, child)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return nil
+	case 1:
+		return kept[0]
+	default:
+		return &Tree{Op: op, Children: kept}
+	}
+}
+
+func And(children ...*Tree) *Tree {
+	return combine(AndOp, children)
+}
+
+func Or(children ...*Tree) *Tree {
+	return combine(OrOp, children)
+}
+
+// String renders the tree with uppercase course codes, e.g. "CS135 and (MATH135 or MATH145)".
+func (t *Tree) String() string {
+	if t.Op == CourseOp {
+		return strings.ToUpper(t.Code)
+	}
+	parts := make([]string, len(t.Children))
+	for i, child := range t.Children {
+		parts[i] = child.String()
+		if child.Op != CourseOp {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " "+t.Op+" ")
+}
+
+// Evaluate reports whether the courses taken, given by their codes, satisfy the tree.
+// If not, unmet has the smallest clauses that do not hold, e.g. for
+// "CS135 and (MATH135 or MATH145)" with only CS135 taken, "MATH135 or MATH145".
+func (t *Tree) Evaluate(taken map[string]bool) (met bool, unmet []string) {
+	switch t.Op {
+	case CourseOp:
+		if taken[t.Code] {
+			return true, nil
+		}
+		return false, []string{t.String()}
+	case AndOp:
+		for _, child := range t.Children {
+			_, childUnmet := child.Evaluate(taken)
+			unmet = append(unmet, childUnmet...)
+		}
+		return len(unmet) == 0, unmet
+	default: // OrOp
+		for _, child := range t.Children {
+			if childMet, _ := child.Evaluate(taken); childMet {
+				return true, nil
+			}
+		}
+		return false, []string{t.String()}
+	}
+}
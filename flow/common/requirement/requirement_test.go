@@ -0,0 +1,94 @@
+package requirement
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *Tree
+		want *Tree
+	}{
+		{"empty", And(nil, Or()), nil},
+		{"single", Or(nil, Course("cs135")), Course("cs135")},
+		{
+			"flattened",
+			And(And(Course("cs135"), Course("cs136")), Or(Course("math135"), Course("math145"))),
+			&Tree{Op: AndOp, Children: []*Tree{
+				Course("cs135"), Course("cs136"),
+				{Op: OrOp, Children: []*Tree{Course("math135"), Course("math145")}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !cmp.Equal(tt.want, tt.got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, tt.got))
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tree := And(Course("cs136"), Or(Course("math135"), Course("math145")), Or(Course("stat230"), And(Course("stat240"), Course("math128"))))
+	tests := []struct {
+		name      string
+		taken     []string
+		wantMet   bool
+		wantUnmet []string
+	}{
+		{"all", []string{"cs136", "math145", "stat230"}, true, nil},
+		{"nested", []string{"cs136", "math135", "stat240", "math128"}, true, nil},
+		{
+			"none",
+			nil,
+			false,
+			[]string{"CS136", "MATH135 or MATH145", "STAT230 or (STAT240 and MATH128)"},
+		},
+		{"partial", []string{"cs136", "stat240"}, false, []string{"MATH135 or MATH145", "STAT230 or (STAT240 and MATH128)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := make(map[string]bool)
+			for _, code := range tt.taken {
+				taken[code] = true
+			}
+			met, unmet := tree.Evaluate(taken)
+			if met != tt.wantMet {
+				t.Errorf("met = %v, want %v", met, tt.wantMet)
+			}
+			if !cmp.Equal(tt.wantUnmet, unmet) {
+				t.Errorf("unmet mismatch (-want +got):\n%s", cmp.Diff(tt.wantUnmet, unmet))
+			}
+		})
+	}
+}
+
+func TestJson(t *testing.T) {
+	tree := And(Course("cs136"), Or(Course("math135"), Course("math145")))
+	want := `{"op":"and","children":[{"op":"course","code":"cs136"},` +
+		`{"op":"or","children":[{"op":"course","code":"math135"},{"op":"course","code":"math145"}]}]}`
+
+	got, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("marshaling: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	var decoded Tree
+	err = json.Unmarshal(got, &decoded)
+	if err != nil {
+		t.Fatalf("unmarshaling: %v", err)
+	}
+	if !cmp.Equal(tree, &decoded) {
+		t.Errorf("round trip mismatch (-want +got):\n%s", cmp.Diff(tree, &decoded))
+	}
+}
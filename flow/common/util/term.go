@@ -61,9 +61,24 @@ func TermSeasonYearToId(maybeSeason string, maybeYear string) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("not a year: %s", maybeYear)
 	}
+	// Quest ids are positive and have at most four digits
+	if year < 1900 || year >= 2900 {
+		return 0, fmt.Errorf("year out of range: %d", year)
+	}
 	return (year-1900)*10 + month, nil
 }
 
+// Whether termId could have been returned by TermSeasonYearToId
+func IsValidTermId(termId int) bool {
+	seasons := map[int]string{1: "Winter", 5: "Spring", 9: "Fall"}
+	season, ok := seasons[termId%10]
+	if !ok {
+		return false
+	}
+	id, err := TermSeasonYearToId(season, strconv.Itoa(TermIdToYear(termId)))
+	return err == nil && id == termId
+}
+
 // Quest id of a term given underscore-separated year and moth, e.g. "2019_09".
 func TermYearMonthToId(yearMonth string) (int, error) {
 	components := strings.Split(yearMonth, "_")
@@ -39,13 +39,25 @@ func TestTermNameToId(t *testing.T) {
 	inputs := []string{
 		"Fall 2019", "Spring 2020", "Winter 2001",
 		"Summer 2020", "1195", "Winter", "2015 Spring",
+		"Fall 0000", "Winter 9999",
 	}
 	want := []util.Outcome{
 		{Value: 1199}, {Value: 1205}, {Value: 1011},
 		{Error: true}, {Error: true}, {Error: true}, {Error: true},
+		{Error: true}, {Error: true},
 	}
 	for i, input := range inputs {
 		got, err := util.TermNameToId(input)
 		want[i].Test(t, input, got, err)
 	}
 }
+
+func TestIsValidTermId(t *testing.T) {
+	inputs := []int{1199, 1011, 9, 9999, 10009, 1198, -1}
+	want := []bool{true, true, true, true, false, false, false}
+	for i, input := range inputs {
+		if got := util.IsValidTermId(input); got != want[i] {
+			t.Errorf("IsValidTermId(%d): got %v; want %v", input, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package util
+
+import (
+	"log"
+	"time"
+)
+
+// UniversityLocation is the time zone of the university,
+// in which its dates and class times are given.
+var UniversityLocation *time.Location
+
+func init() {
+	var err error
+	UniversityLocation, err = time.LoadLocation("America/Toronto")
+	if err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+}
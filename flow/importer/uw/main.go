@@ -13,6 +13,7 @@ import (
 	"flow/importer/uw/api"
 	"flow/importer/uw/cron"
 	"flow/importer/uw/parts/course"
+	"flow/importer/uw/parts/exam"
 	"flow/importer/uw/parts/term"
 )
 
@@ -49,8 +50,8 @@ func RunVacuum(state *state.State, vacuums ...VacuumFunc) bool {
 	return ok
 }
 
-var HourlyFuncs = []ImportFunc{term.ImportAll, course.ImportAll}
-var VacuumFuncs = []VacuumFunc{term.Vacuum, course.Vacuum}
+var HourlyFuncs = []ImportFunc{term.ImportAll, course.ImportAll, exam.ImportAll}
+var VacuumFuncs = []VacuumFunc{term.Vacuum, course.Vacuum, exam.Vacuum}
 
 // monitorSpec holds the Sentry-specific tuning for a scheduled action. The
 // schedule itself is not stored here: it is read from the crontab at runtime
@@ -64,7 +65,7 @@ type monitorSpec struct {
 }
 
 // monitors lists the scheduled actions that report to Sentry Crons. Manual
-// runs ("courses", "terms") are intentionally omitted.
+// runs ("courses", "terms", "exams") are intentionally omitted.
 var monitors = map[string]monitorSpec{
 	"hourly": {maxRuntime: 360},
 	"vacuum": {maxRuntime: 30},
@@ -152,6 +153,8 @@ func main() {
 		})
 	case "terms":
 		RunImport(state, client, term.ImportAll)
+	case "exams":
+		RunImport(state, client, exam.ImportAll)
 	case "vacuum":
 		withMonitor("vacuum", func() bool {
 			return RunVacuum(state, VacuumFuncs...)
@@ -225,9 +225,56 @@ func convertAll(
 		}
 	}
 
+	convertDeliveryModes(dst)
 	return nil
 }
 
+// convertDeliveryModes summarizes the IsOnline flags of each course's sections
+// in a given term: a course is BOTH if it has online and in-person sections.
+func convertDeliveryModes(dst *convertResult) {
+	type modeCount struct {
+		online   int
+		inPerson int
+	}
+	var keys []courseTerm
+	counts := make(map[courseTerm]*modeCount)
+
+	for _, section := range dst.Sections {
+		key := courseTerm{section.CourseCode, section.TermId}
+		count, found := counts[key]
+		if !found {
+			count = new(modeCount)
+			counts[key] = count
+			keys = append(keys, key)
+		}
+		if section.IsOnline {
+			count.online++
+		} else {
+			count.inPerson++
+		}
+	}
+
+	for _, key := range keys {
+		var mode string
+		count := counts[key]
+		if count.online > 0 && count.inPerson > 0 {
+			mode = bothModes
+		} else if count.online > 0 {
+			mode = onlineOnly
+		} else {
+			mode = inPersonOnly
+		}
+		dst.DeliveryModes = append(
+			dst.DeliveryModes,
+			deliveryMode{
+				CourseCode:   key.CourseCode,
+				TermId:       key.TermId,
+				DeliveryMode: mode,
+			},
+		)
+	}
+}
+
 func convertCourse(dst *convertResult, apiCourse *apiCourse) error {
 	courseCode := strings.ToLower(apiCourse.Subject + apiCourse.Number)
 	newCourse := course{
@@ -255,6 +302,7 @@ func convertCourse(dst *convertResult, apiCourse *apiCourse) error {
 			String: prereqString,
 			Valid:  true,
 		}
+		newCourse.PrereqTree = buildRequirementTree(prereqString)
 
 		for _, prereqCode := range prereqCodes {
 			dst.Prereqs = append(
@@ -275,6 +323,7 @@ func convertCourse(dst *convertResult, apiCourse *apiCourse) error {
 			String: coreqString,
 			Valid:  true,
 		}
+		newCourse.CoreqTree = buildRequirementTree(coreqString)
 
 		for _, coreqCode := range coreqCodes {
 			dst.Prereqs = append(
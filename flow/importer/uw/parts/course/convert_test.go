@@ -2,6 +2,7 @@ package course
 
 import (
 	"encoding/json"
+	"flow/common/requirement"
 	"flow/importer/uw/parts/term"
 	"testing"
 	"time"
@@ -94,6 +95,13 @@ func TestConvertCourse(t *testing.T) {
 							String: "STAT220, STAT240",
 							Valid:  true,
 						},
+						PrereqTree: requirement.Or(
+							requirement.Course("math116"), requirement.Course("math117"),
+							requirement.Course("math137"), requirement.Course("math147"),
+							requirement.Course("math128"),
+							requirement.Course("math118"), requirement.Course("math119"),
+							requirement.Course("math138"), requirement.Course("math148"),
+						),
 					},
 				},
 				Prereqs: []prereq{
@@ -572,6 +580,74 @@ func TestParsePrereqs(t *testing.T) {
 	}
 }
 
+func TestBuildRequirementTree(t *testing.T) {
+	course := requirement.Course
+	tests := []struct {
+		name  string
+		input string
+		want  *requirement.Tree
+	}{
+		{
+			"list",
+			"ECE106, ECE140, MATH119; Level at least 2A Computer Engineering or Electrical Engineering.",
+			requirement.And(course("ece106"), course("ece140"), course("math119")),
+		},
+		{
+			"one_of",
+			"One of ECON221, STAT211, STAT231, STAT241; AFM241 or CS330; Accounting and Financial Management, Mathematics/CPA, or Biotechnology/CPA students.",
+			requirement.And(
+				requirement.Or(course("econ221"), course("stat211"), course("stat231"), course("stat241")),
+				requirement.Or(course("afm241"), course("cs330")),
+			),
+		},
+		{
+			"conjunctions",
+			"MATH235 or MATH245, MATH237 or MATH247.",
+			requirement.And(
+				requirement.Or(course("math235"), course("math245")),
+				requirement.Or(course("math237"), course("math247")),
+			),
+		},
+		{
+			"parentheses",
+			"ECE240, (ECE205 or MATH211); Level at least 2B Computer Engineering.",
+			requirement.And(course("ece240"), requirement.Or(course("ece205"), course("math211"))),
+		},
+		{
+			"slashes",
+			"RS305A/RS333(GRK233/RS233)",
+			requirement.And(
+				requirement.Or(course("rs305a"), course("rs333")),
+				requirement.Or(course("grk233"), course("rs233")),
+			),
+		},
+		{
+			"non_course_alternative",
+			"ECE650 or ECE750 Tpc 26 or instructor consent.",
+			nil,
+		},
+		{
+			"unbalanced",
+			"CS136) and (CS245",
+			requirement.And(course("cs136"), course("cs245")),
+		},
+		{
+			"no_courses",
+			"Level at least 3A Honours Philosophy students",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildRequirementTree(tt.input)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
 func TestParseRequirements(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -639,3 +715,34 @@ func TestParseRequirements(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertDeliveryModes(t *testing.T) {
+	termCode := "1265"
+	component := "LEC"
+	idToTerm := map[int]*term.Term{1265: {Id: 1265}}
+
+	// Sections numbered X8X are online, as per convertSection
+	classes := []apiClass{
+		{CourseCode: "cs135", ClassNumber: 1, SectionNumber: 1, CourseComponent: &component, TermId: &termCode},
+		{CourseCode: "cs135", ClassNumber: 2, SectionNumber: 81, CourseComponent: &component, TermId: &termCode},
+		{CourseCode: "cs136", ClassNumber: 3, SectionNumber: 81, CourseComponent: &component, TermId: &termCode},
+		{CourseCode: "cs136", ClassNumber: 4, SectionNumber: 82, CourseComponent: &component, TermId: &termCode},
+		{CourseCode: "math135", ClassNumber: 5, SectionNumber: 2, CourseComponent: &component, TermId: &termCode},
+	}
+
+	var got convertResult
+	err := convertAll(&got, nil, classes, idToTerm)
+	if err != nil {
+		t.Errorf("error: %v", err)
+	}
+
+	want := []deliveryMode{
+		{CourseCode: "cs135", TermId: 1265, DeliveryMode: bothModes},
+		{CourseCode: "cs136", TermId: 1265, DeliveryMode: onlineOnly},
+		{CourseCode: "math135", TermId: 1265, DeliveryMode: inPersonOnly},
+	}
+	if !cmp.Equal(want, got.DeliveryModes) {
+		diff := cmp.Diff(want, got.DeliveryModes)
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -22,40 +22,56 @@ type courseOffer struct {
 	offerNumber int
 }
 
-func fetchAll(client *api.Client, termIds []int) ([]apiCourse, []apiClass, error) {
+func fetchAll(client *api.Client, termIds []int) ([]apiCourse, []apiClass, []courseTerm, error) {
 	// Fetch course data
 	courses, err := fetchCourses(client, termIds)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch courses: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to fetch courses: %w", err)
 	}
 
+	// Requests are kept sequential (one at a time) with a small inter-request
+	// delay to avoid triggering API rate limits.
+	classes, fetched := fetchClasses(courses, termIds, func(course *apiCourse, termId int) ([]apiClass, error) {
+		defer time.Sleep(classRequestDelay)
+		return fetchClass(client, course, termId)
+	})
+	return courses, classes, fetched, nil
+}
+
+// fetchClasses fetches class schedules for all given courses in the given terms.
+// Failed requests are logged and skipped, so it also returns the courses and terms
+// whose classes were fetched: only for these do we know all classes there are.
+func fetchClasses(
+	courses []apiCourse,
+	termIds []int,
+	fetch func(course *apiCourse, termId int) ([]apiClass, error),
+) ([]apiClass, []courseTerm) {
 	// Cross-listed codes (e.g. SOC 327 and LS 327) share a courseId, and
 	// ClassSchedules/{term}/{courseId} returns the sections of all of them.
 	// Fetch each courseId only once per term so the same section is never
 	// imported twice, and map each returned class back to its course code
 	// via its courseOfferNumber.
 	offerToCode := make(map[courseOffer]string)
-	seenCourseId := make(map[string]bool)
+	idToCodes := make(map[string][]string)
 	var uniqueCourses []apiCourse
 	for _, course := range courses {
+		code := strings.ToLower(course.Subject + course.Number)
 		offer := courseOffer{course.CourseId, course.CourseOfferNumber}
-		offerToCode[offer] = strings.ToLower(course.Subject + course.Number)
-		if !seenCourseId[course.CourseId] {
-			seenCourseId[course.CourseId] = true
+		offerToCode[offer] = code
+		if _, seen := idToCodes[course.CourseId]; !seen {
 			uniqueCourses = append(uniqueCourses, course)
 		}
+		idToCodes[course.CourseId] = append(idToCodes[course.CourseId], code)
 	}
 
-	// Fetch class schedules for all returned courses, for upcoming terms.
-	// Requests are kept sequential (one at a time) with a small inter-request
-	// delay to avoid triggering API rate limits.
 	var classes []apiClass
+	var fetched []courseTerm
 	numClasses := len(uniqueCourses) * len(termIds)
 	numFetched := 0
 
 	for _, course := range uniqueCourses {
 		for _, termId := range termIds {
-			fetched, err := fetchClass(client, &course, termId)
+			courseClasses, err := fetch(&course, termId)
 			numFetched++
 			if numFetched%500 == 0 {
 				log.Warnf("fetched %d/%d class schedules", numFetched, numClasses)
@@ -63,23 +79,24 @@ func fetchAll(client *api.Client, termIds []int) ([]apiCourse, []apiClass, error
 
 			if err != nil {
 				log.Warnf("failed to fetch section with error %s, proceeding anyway", err)
-			} else {
-				for _, class := range fetched {
-					offer := courseOffer{class.CourseId, class.CourseOfferNumber}
-					if code, ok := offerToCode[offer]; ok {
-						class.CourseCode = code
-					} else {
-						class.CourseCode = strings.ToLower(course.Subject + course.Number)
-					}
-					classes = append(classes, class)
+				continue
+			}
+			for _, code := range idToCodes[course.CourseId] {
+				fetched = append(fetched, courseTerm{CourseCode: code, TermId: termId})
+			}
+			for _, class := range courseClasses {
+				offer := courseOffer{class.CourseId, class.CourseOfferNumber}
+				if code, ok := offerToCode[offer]; ok {
+					class.CourseCode = code
+				} else {
+					class.CourseCode = strings.ToLower(course.Subject + course.Number)
 				}
+				classes = append(classes, class)
 			}
-
-			time.Sleep(classRequestDelay)
 		}
 	}
 
-	return courses, classes, nil
+	return classes, fetched
 }
 
 func fetchClass(client *api.Client, course *apiCourse, termId int) ([]apiClass, error) {
@@ -0,0 +1,53 @@
+package course
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFetchClassesWithFailure(t *testing.T) {
+	courses := []apiCourse{
+		{CourseId: "001", CourseOfferNumber: 1, Subject: "CS", Number: "135"},
+		// Cross-listed, so fetched once for both codes
+		{CourseId: "002", CourseOfferNumber: 1, Subject: "SOC", Number: "327"},
+		{CourseId: "002", CourseOfferNumber: 2, Subject: "LS", Number: "327"},
+	}
+	termIds := []int{1265, 1269}
+
+	var requests int
+	fetch := func(course *apiCourse, termId int) ([]apiClass, error) {
+		requests++
+		// As if rate limited
+		if course.CourseId == "002" && termId == 1269 {
+			return nil, errors.New("429 Too Many Requests")
+		}
+		return []apiClass{
+			{CourseId: course.CourseId, CourseOfferNumber: 2, ClassNumber: termId},
+		}, nil
+	}
+
+	classes, fetched := fetchClasses(courses, termIds, fetch)
+	if requests != 4 {
+		t.Errorf("expected 4 requests, got %d", requests)
+	}
+
+	var gotClasses []courseTerm
+	for _, class := range classes {
+		gotClasses = append(gotClasses, courseTerm{class.CourseCode, class.ClassNumber})
+	}
+	wantClasses := []courseTerm{{"cs135", 1265}, {"cs135", 1269}, {"ls327", 1265}}
+	if !cmp.Equal(wantClasses, gotClasses) {
+		t.Errorf("classes mismatch (-want +got):\n%s", cmp.Diff(wantClasses, gotClasses))
+	}
+
+	// Neither code of the failed request may have its delivery mode cleared in 1269
+	wantFetched := []courseTerm{
+		{"cs135", 1265}, {"cs135", 1269},
+		{"soc327", 1265}, {"ls327", 1265},
+	}
+	if !cmp.Equal(wantFetched, fetched) {
+		t.Errorf("fetched mismatch (-want +got):\n%s", cmp.Diff(wantFetched, fetched))
+	}
+}
@@ -33,7 +33,7 @@ func ImportAll(state *state.State, client *api.Client) error {
 
 	}
 
-	courses, classes, err := fetchAll(client, termIds)
+	courses, classes, fetched, err := fetchAll(client, termIds)
 	if err != nil {
 		return fmt.Errorf("failed to fetch courses: %w", err)
 	}
@@ -81,5 +81,12 @@ func ImportAll(state *state.State, client *api.Client) error {
 	}
 	log.EndImport("section_meeting", result)
 
+	log.StartImport("course_term_delivery_modes")
+	result, err = insertAllDeliveryModes(state.Db, converted.DeliveryModes, fetched)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery modes: %w", err)
+	}
+	log.EndImport("course_term_delivery_modes", result)
+
 	return nil
 }
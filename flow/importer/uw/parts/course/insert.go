@@ -16,16 +16,18 @@ UPDATE course SET
   description = delta.description,
   prereqs = delta.prereqs,
   coreqs = delta.coreqs,
-  antireqs = delta.antireqs
+  antireqs = delta.antireqs,
+  prereq_tree = delta.prereq_tree,
+  coreq_tree = delta.coreq_tree
 FROM work.course_delta delta
 WHERE course.code = delta.code
 AND NOT course.authoritative
 `
 
 const insertCourseQuery = `
-INSERT INTO course(code, name, description, prereqs, coreqs, antireqs)
+INSERT INTO course(code, name, description, prereqs, coreqs, antireqs, prereq_tree, coreq_tree)
 SELECT
-  d.code, d.name, d.description, d.prereqs, d.coreqs, d.antireqs
+  d.code, d.name, d.description, d.prereqs, d.coreqs, d.antireqs, d.prereq_tree, d.coreq_tree
 FROM work.course_delta d
   LEFT JOIN course c ON c.code = d.code
 WHERE c.id IS NULL
@@ -446,3 +448,119 @@ func insertAllProfs(conn *db.Conn, profs profMap) (*log.DbResult, error) {
 	result.Untouched = len(preparedProfs) - result.Inserted
 	return &result, nil
 }
+
+const truncateDeliveryModeQuery = `TRUNCATE work.course_term_delivery_mode_delta`
+
+// Classes of the courses in the terms given by $1 and $2 were fetched in full,
+// so any of their rows which are absent from the delta correspond to courses
+// no longer offered. Rows of courses whose classes could not be fetched are kept.
+const clearDeliveryModeQuery = `
+DELETE FROM course_term_delivery_modes m
+USING course c, UNNEST($1::TEXT[], $2::INT[]) AS f(course_code, term_id)
+WHERE c.id = m.course_id
+  AND f.course_code = c.code
+  AND f.term_id = m.term_id
+  AND NOT EXISTS (
+    SELECT FROM work.course_term_delivery_mode_delta d
+    WHERE d.course_code = c.code
+      AND d.term_id = m.term_id
+  )
+`
+
+const updateDeliveryModeQuery = `
+UPDATE course_term_delivery_modes SET
+  delivery_mode = delta.delivery_mode,
+  updated_at = NOW()
+FROM work.course_term_delivery_mode_delta delta
+  JOIN course c ON c.code = delta.course_code
+WHERE course_term_delivery_modes.course_id = c.id
+  AND course_term_delivery_modes.term_id = delta.term_id
+  AND course_term_delivery_modes.delivery_mode != delta.delivery_mode
+`
+
+const insertDeliveryModeQuery = `
+INSERT INTO course_term_delivery_modes(course_id, term_id, delivery_mode)
+SELECT
+  c.id, d.term_id, d.delivery_mode
+FROM work.course_term_delivery_mode_delta d
+  JOIN course c ON c.code = d.course_code
+  -- term must have been imported beforehand
+  JOIN term t ON t.id = d.term_id
+  LEFT JOIN course_term_delivery_modes m
+    ON m.course_id = c.id
+   AND m.term_id = d.term_id
+WHERE m.course_id IS NULL
+`
+
+const countRejectedDeliveryModeQuery = `
+SELECT COUNT(*)
+FROM work.course_term_delivery_mode_delta d
+  LEFT JOIN course c ON c.code = d.course_code
+  LEFT JOIN term t ON t.id = d.term_id
+WHERE c.id IS NULL OR t.id IS NULL
+`
+
+func insertAllDeliveryModes(conn *db.Conn, modes []deliveryMode, fetched []courseTerm) (*log.DbResult, error) {
+	var result log.DbResult
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return &result, fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(truncateDeliveryModeQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to truncate work table: %w", err)
+	}
+
+	preparedModes := make([][]interface{}, len(modes))
+	for i, mode := range modes {
+		preparedModes[i] = util.AsSlice(mode)
+	}
+
+	_, err = tx.CopyFrom(
+		db.Identifier{"work", "course_term_delivery_mode_delta"},
+		util.Fields(modes),
+		preparedModes,
+	)
+	if err != nil {
+		return &result, fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	fetchedCodes := make([]string, len(fetched))
+	fetchedTermIds := make([]int, len(fetched))
+	for i, ct := range fetched {
+		fetchedCodes[i], fetchedTermIds[i] = ct.CourseCode, ct.TermId
+	}
+	_, err = tx.Exec(clearDeliveryModeQuery, fetchedCodes, fetchedTermIds)
+	if err != nil {
+		return &result, fmt.Errorf("failed to clean up target table: %w", err)
+	}
+
+	tag, err := tx.Exec(updateDeliveryModeQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to apply update: %w", err)
+	}
+	result.Updated = int(tag.RowsAffected())
+
+	tag, err = tx.Exec(insertDeliveryModeQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to insert: %w", err)
+	}
+	result.Inserted = int(tag.RowsAffected())
+
+	err = tx.QueryRow(countRejectedDeliveryModeQuery).Scan(&result.Rejected)
+	if err != nil {
+		return &result, fmt.Errorf("failed to count rejected: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return &result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Unlike with sections, rows whose mode did not change are left alone
+	result.Untouched = len(modes) - result.Inserted - result.Updated - result.Rejected
+	return &result, nil
+}
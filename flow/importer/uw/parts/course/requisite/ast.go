@@ -0,0 +1,147 @@
+package requisite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is a node of the syntax tree of requisites.
+// Its String form is compact and meant for tests and debugging.
+type Node interface {
+	fmt.Stringer
+	node()
+}
+
+// Course is a course code in lowercase, e.g. cs135
+type Course struct {
+	Code string
+}
+
+// AllOf holds if all of its children hold
+type AllOf struct {
+	Children []Node
+}
+
+// OneOf holds if at least one of its children holds
+type OneOf struct {
+	Children []Node
+}
+
+// MinGrade requires a grade of at least Percent in the courses of Child,
+// e.g. "MATH 128 with a minimum grade of 70%"
+type MinGrade struct {
+	Percent int
+	Child   Node
+}
+
+// Level requires the student to be in at least the given level, e.g. 2A
+type Level struct {
+	Min string
+}
+
+// Program restricts the requisite to students of some programs,
+// e.g. "Honours Mathematics students only". Name is the phrase as written.
+type Program struct {
+	Name string
+}
+
+// Text is a phrase that we do not understand, e.g. "instructor consent"
+type Text struct {
+	Text string
+}
+
+func (*Course) node()   {}
+func (*AllOf) node()    {}
+func (*OneOf) node()    {}
+func (*MinGrade) node() {}
+func (*Level) node()    {}
+func (*Program) node()  {}
+func (*Text) node()     {}
+
+func (n *Course) String() string {
+	return strings.ToUpper(n.Code)
+}
+
+func joinNodes(name string, children []Node) string {
+	parts := make([]string, len(children))
+	for i, child := range children {
+		parts[i] = child.String()
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (n *AllOf) String() string {
+	return joinNodes("all", n.Children)
+}
+
+func (n *OneOf) String() string {
+	return joinNodes("one", n.Children)
+}
+
+func (n *MinGrade) String() string {
+	return fmt.Sprintf("grade(%d%%, %s)", n.Percent, n.Child)
+}
+
+func (n *Level) String() string {
+	return "level(" + n.Min + ")"
+}
+
+func (n *Program) String() string {
+	return fmt.Sprintf("program(%q)", n.Name)
+}
+
+func (n *Text) String() string {
+	return fmt.Sprintf("text(%q)", n.Text)
+}
+
+// allOf combines nodes into an AllOf, dropping nils
+// and collapsing a single child into itself.
+func allOf(nodes ...Node) Node {
+	children := flatten(nodes, func(node Node) []Node {
+		if all, ok := node.(*AllOf); ok {
+			return all.Children
+		}
+		return nil
+	})
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	default:
+		return &AllOf{Children: children}
+	}
+}
+
+// oneOf is like allOf, but for OneOf
+func oneOf(nodes ...Node) Node {
+	children := flatten(nodes, func(node Node) []Node {
+		if one, ok := node.(*OneOf); ok {
+			return one.Children
+		}
+		return nil
+	})
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	default:
+		return &OneOf{Children: children}
+	}
+}
+
+func flatten(nodes []Node, inner func(Node) []Node) []Node {
+	var children []Node
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		if grandchildren := inner(node); grandchildren != nil {
+			children = append(children, grandchildren...)
+		} else {
+			children = append(children, node)
+		}
+	}
+	return children
+}
@@ -0,0 +1,285 @@
+package requisite
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive descent parser for requisites. From loosest to tightest:
+//
+//	list   := clause ((";" | ".") clause)*
+//	clause := or ("," ["or" | "and"] or)*
+//	or     := and ("or" and)*
+//	and    := alt ("and" alt)*
+//	alt    := unit ("/" unit)*
+//	unit   := GRADE unit | primary [GRADE | PHRASE] | PHRASE
+//	primary:= CODE | "(" list ")" | ("one of" | "all of") clause
+//
+// where GRADE and PHRASE are runs of words, GRADE being one that mentions
+// a minimum grade. Clauses without course codes are not parsed,
+// but recognized as a whole as level or program restrictions.
+type parser struct {
+	text   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() int {
+	if p.pos >= len(p.tokens) {
+		return endToken
+	}
+	return p.tokens[p.pos].kind
+}
+
+// sequence parses items separated by the given token and combines them with op.
+func (p *parser) sequence(separator int, item func() Node, op func(...Node) Node) Node {
+	children := []Node{item()}
+	for p.peek() == separator {
+		p.pos++
+		children = append(children, item())
+	}
+	return op(children...)
+}
+
+func (p *parser) list() Node {
+	var clauses []Node
+	for {
+		switch p.peek() {
+		case endToken, closeToken:
+			return allOf(clauses...)
+		case clauseToken:
+			p.pos++
+		default:
+			clauses = append(clauses, p.clause())
+		}
+	}
+}
+
+// clauseEnd returns the position of the token that ends the clause at p.pos
+// and whether the clause names any course codes.
+func (p *parser) clauseEnd() (int, bool) {
+	depth := 0
+	hasCode := false
+	for i := p.pos; i < len(p.tokens); i++ {
+		switch p.tokens[i].kind {
+		case openToken:
+			depth++
+		case closeToken:
+			if depth == 0 {
+				return i, hasCode
+			}
+			depth--
+		case clauseToken:
+			if depth == 0 {
+				return i, hasCode
+			}
+		case codeToken:
+			hasCode = true
+		}
+	}
+	return len(p.tokens), hasCode
+}
+
+func (p *parser) clause() Node {
+	end, hasCode := p.clauseEnd()
+	if !hasCode {
+		text := p.text[p.tokens[p.pos].start:p.tokens[end-1].end]
+		p.pos = end
+		return restriction(text)
+	}
+
+	var items []Node
+	for p.pos < end {
+		start := p.pos
+		// Juxtaposed items, e.g. "CS136 CS136L", must all hold
+		items = append(items, p.commaList(allOf))
+		// The token could not be parsed at all if nothing was consumed
+		if p.pos == start {
+			p.pos++
+		}
+	}
+	return allOf(items...)
+}
+
+// commaList parses a comma-separated list, where "A, B, or C" means one of them.
+func (p *parser) commaList(op func(...Node) Node) Node {
+	children := []Node{p.or()}
+	for p.peek() == commaToken {
+		p.pos++
+		switch p.peek() {
+		case orToken:
+			p.pos++
+			op = oneOf
+		case andToken:
+			p.pos++
+		}
+		children = append(children, p.or())
+	}
+	return op(children...)
+}
+
+func (p *parser) or() Node {
+	return p.sequence(orToken, p.and, oneOf)
+}
+
+func (p *parser) and() Node {
+	return p.sequence(andToken, p.alt, allOf)
+}
+
+func (p *parser) alt() Node {
+	return p.sequence(slashToken, p.unit, oneOf)
+}
+
+func (p *parser) unit() Node {
+	if p.peek() == wordToken {
+		text := p.phrase()
+		if percent, ok := minGrade(text); ok && p.startsPrimary() {
+			return &MinGrade{Percent: percent, Child: p.unit()}
+		}
+		return phraseNode(text)
+	}
+
+	node := p.primary()
+	if node == nil || p.peek() != wordToken {
+		return node
+	}
+	text := p.phrase()
+	if percent, ok := minGrade(text); ok {
+		return &MinGrade{Percent: percent, Child: node}
+	}
+	// Qualifiers such as "taken prior to Fall 2008" are kept alongside
+	return allOf(node, phraseNode(text))
+}
+
+func (p *parser) startsPrimary() bool {
+	switch p.peek() {
+	case codeToken, openToken, oneOfToken, allOfToken:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *parser) primary() Node {
+	switch p.peek() {
+	case codeToken:
+		tok := p.tokens[p.pos]
+		p.pos++
+		return &Course{Code: strings.ToLower(p.text[tok.start:tok.end])}
+	case openToken:
+		p.pos++
+		node := p.list()
+		// Unbalanced parentheses are not unheard of, so the close is optional
+		if p.peek() == closeToken {
+			p.pos++
+		}
+		return node
+	case oneOfToken:
+		p.pos++
+		return p.commaList(oneOf)
+	case allOfToken:
+		p.pos++
+		return p.commaList(allOf)
+	default:
+		return nil
+	}
+}
+
+// Words that follow "or" in grade phrases, e.g. "60% or higher"
+var orHigherWords = map[string]bool{"higher": true, "better": true, "above": true}
+
+// phrase consumes a run of words and returns their text.
+func (p *parser) phrase() string {
+	first := p.tokens[p.pos]
+	last := first
+	for p.peek() == wordToken {
+		last = p.tokens[p.pos]
+		p.pos++
+		// "or higher" does not start an alternative
+		if p.peek() == orToken && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == wordToken {
+			next := p.tokens[p.pos+1]
+			if orHigherWords[strings.ToLower(p.text[next.start:next.end])] {
+				last = next
+				p.pos += 2
+			}
+		}
+	}
+	return p.text[first.start:last.end]
+}
+
+var (
+	gradeRegexp   = regexp.MustCompile(`(?i)\b(?:grade|mark)\b\D*?([0-9]{1,3})%`)
+	levelRegexp   = regexp.MustCompile(`(?i)\blevel (?:at least )?([1-5][A-D])\b`)
+	programRegexp = regexp.MustCompile(`(?i)\b(?:students?|honours|program|plan|faculty|majors?)\b`)
+)
+
+func minGrade(text string) (int, bool) {
+	match := gradeRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return 0, false
+	}
+	// The regexp ensures at most three digits
+	percent, _ := strconv.Atoi(match[1])
+	return percent, true
+}
+
+// Words that carry no meaning of their own, e.g. "either" in "either CS 135 or CS 145"
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "any": true, "at": true, "least": true, "either": true, "both": true,
+	"the": true, "following": true, "in": true, "of": true, "must": true, "have": true,
+	"completed": true, "complete": true, "completion": true, "credit": true, "for": true,
+}
+
+// phraseNode classifies a phrase between course codes. Filler phrases are nil.
+func phraseNode(text string) Node {
+	filler := true
+	for _, word := range strings.Fields(text) {
+		if !fillerWords[strings.ToLower(word)] {
+			filler = false
+			break
+		}
+	}
+	if filler {
+		return nil
+	}
+	if match := levelRegexp.FindStringSubmatch(text); match != nil {
+		return &Level{Min: strings.ToUpper(match[1])}
+	}
+	return &Text{Text: text}
+}
+
+// restriction classifies a clause without course codes,
+// e.g. "Level at least 2A Computer Engineering or Electrical Engineering".
+func restriction(text string) Node {
+	text = strings.TrimSpace(text)
+	if match := levelRegexp.FindStringSubmatchIndex(text); match != nil {
+		level := &Level{Min: strings.ToUpper(text[match[2]:match[3]])}
+		// Whatever else is in the clause names the programs
+		rest := strings.TrimSpace(text[:match[0]] + " " + text[match[1]:])
+		if rest == "" {
+			return level
+		}
+		return allOf(level, &Program{Name: rest})
+	}
+	if programRegexp.MatchString(text) {
+		return &Program{Name: text}
+	}
+	return &Text{Text: text}
+}
+
+// Parse parses requisites into a syntax tree. Course codes must be written
+// in full and without spaces, e.g. "CS135 or CS145", as in the output of
+// expandCourseCodes in the course importer. Parse is lenient: it does not fail,
+// but represents whatever it does not understand as Text nodes.
+// The result is nil if the text is empty.
+func Parse(text string) Node {
+	p := parser{text: text, tokens: tokenize(text)}
+	node := p.list()
+	// Stray close parentheses end the list early, so we skip them and go on
+	for p.peek() != endToken {
+		p.pos++
+		node = allOf(node, p.list())
+	}
+	return node
+}
@@ -0,0 +1,83 @@
+package requisite
+
+import (
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	text := "One of CS135, CS145 (60%); Level at least 2A Math students 1.5 years."
+	want := []int{
+		oneOfToken, codeToken, commaToken, codeToken, openToken, wordToken, closeToken,
+		clauseToken, wordToken, wordToken, wordToken, wordToken, wordToken, wordToken, wordToken, wordToken, clauseToken,
+	}
+
+	tokens := tokenize(text)
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens; want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if tok.kind != want[i] {
+			t.Errorf("token %d %q has kind %d; want %d", i, text[tok.start:tok.end], tok.kind, want[i])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"unbalanced",
+			"CS136) and (CS245",
+			"all(CS136, CS245)",
+		},
+		{
+			"brackets",
+			"[CS136 or CS146] and MATH135",
+			"all(one(CS136, CS146), MATH135)",
+		},
+		{
+			"all_of",
+			"All of CS240, CS241 or one of CS245, CS246",
+			"all(CS240, one(CS241, CS245, CS246))",
+		},
+		{
+			"grade_or_better",
+			"CS135 with a grade of 75% or better or CS145",
+			"one(grade(75%, CS135), CS145)",
+		},
+		{
+			"level_between_courses",
+			"CS350 and level at least 3B",
+			"all(CS350, level(3B))",
+		},
+		{
+			"grade_without_course",
+			"A minimum grade of 60%",
+			`text("A minimum grade of 60%")`,
+		},
+		{
+			"stray_conjunction",
+			"or CS135",
+			"CS135",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.input)
+			if got == nil {
+				t.Fatalf("got nil tree; want %s", tt.want)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := Parse("  "); got != nil {
+		t.Errorf("got %s for blank text; want nil", got)
+	}
+}
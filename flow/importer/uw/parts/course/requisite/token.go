@@ -0,0 +1,74 @@
+package requisite
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	endToken = iota
+	// Full course codes, e.g. STAT230
+	codeToken
+	openToken
+	closeToken
+	// Semicolons and periods end clauses
+	clauseToken
+	commaToken
+	slashToken
+	orToken
+	andToken
+	// "one of", "all of"
+	oneOfToken
+	allOfToken
+	// Anything else: words, numbers, percentages and levels
+	wordToken
+)
+
+type token struct {
+	kind int
+	// Position of the token in the text
+	start int
+	end   int
+}
+
+var tokenRegexp = regexp.MustCompile(
+	`(?i:\b(?:one|all|both|each) of\b)|\b[A-Z]{2,}[0-9]{3}[A-Z]*\b|[()\[\],;/]|\.(?:\s|$)|[^\s()\[\],;/.]+(?:\.[^\s()\[\],;/.]+)*`,
+)
+
+func tokenize(text string) []token {
+	var tokens []token
+	for _, loc := range tokenRegexp.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(strings.TrimSpace(text[loc[0]:loc[1]]))
+		var kind int
+		switch word {
+		case "(", "[":
+			kind = openToken
+		case ")", "]":
+			kind = closeToken
+		case ";", ".":
+			kind = clauseToken
+		case ",":
+			kind = commaToken
+		case "/":
+			kind = slashToken
+		case "or":
+			kind = orToken
+		case "and", "&":
+			kind = andToken
+		case "one of":
+			kind = oneOfToken
+		case "all of", "both of", "each of":
+			kind = allOfToken
+		default:
+			if codeRegexp.MatchString(text[loc[0]:loc[1]]) {
+				kind = codeToken
+			} else {
+				kind = wordToken
+			}
+		}
+		tokens = append(tokens, token{kind: kind, start: loc[0], end: loc[1]})
+	}
+	return tokens
+}
+
+var codeRegexp = regexp.MustCompile(`^[A-Z]{2,}[0-9]{3}[A-Z]*$`)
@@ -0,0 +1,72 @@
+package course
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"flow/importer/uw/parts/course/requisite"
+)
+
+// courseCodes lists the codes of the courses in a syntax tree
+func courseCodes(node requisite.Node) []string {
+	switch node := node.(type) {
+	case *requisite.Course:
+		return []string{node.Code}
+	case *requisite.AllOf:
+		var codes []string
+		for _, child := range node.Children {
+			codes = append(codes, courseCodes(child)...)
+		}
+		return codes
+	case *requisite.OneOf:
+		var codes []string
+		for _, child := range node.Children {
+			codes = append(codes, courseCodes(child)...)
+		}
+		return codes
+	case *requisite.MinGrade:
+		return courseCodes(node.Child)
+	default:
+		return nil
+	}
+}
+
+func TestParseRequisiteCorpus(t *testing.T) {
+	data, err := os.ReadFile("testdata/requisites.txt")
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+
+	for _, entry := range strings.Split(string(data), "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(entry), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if len(lines) != 2 {
+			t.Fatalf("malformed corpus entry: %q", entry)
+		}
+
+		input, want := lines[0], lines[1]
+		t.Run(input, func(t *testing.T) {
+			expanded, wantCodes := expandCourseCodes(input)
+			node := requisite.Parse(expanded)
+			if node == nil {
+				t.Fatalf("got nil tree")
+			}
+			if got := node.String(); got != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+			// No course may be lost on the way
+			gotCodes := courseCodes(node)
+			if strings.Join(gotCodes, " ") != strings.Join(wantCodes, " ") {
+				t.Errorf("codes %v; want %v", gotCodes, wantCodes)
+			}
+		})
+	}
+}
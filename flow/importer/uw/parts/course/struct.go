@@ -3,6 +3,8 @@ package course
 import (
 	"time"
 
+	"flow/common/requirement"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -13,6 +15,8 @@ type convertResult struct {
 	Sections []section
 	Meetings []meeting
 	Profs    profMap
+	// Derived from Sections, so populated only after all sections are converted
+	DeliveryModes []deliveryMode
 }
 
 type section struct {
@@ -41,6 +45,28 @@ type meeting struct {
 	IsTba        bool
 }
 
+// Values allowed by the delivery_mode check on course_term_delivery_modes.
+// The check also allows N_A, which the importer deliberately never writes:
+// courses without sections in a term get no row at all, and N_A is left
+// to manual entry for courses that are offered but have no delivery mode.
+const (
+	onlineOnly   = "ONLINE_ONLY"
+	inPersonOnly = "IN_PERSON_ONLY"
+	bothModes    = "BOTH"
+)
+
+// courseTerm identifies the classes of a course in a term
+type courseTerm struct {
+	CourseCode string
+	TermId     int
+}
+
+type deliveryMode struct {
+	CourseCode   string
+	TermId       int
+	DeliveryMode string
+}
+
 type profMap map[string]string // code -> name
 
 type course struct {
@@ -50,6 +76,9 @@ type course struct {
 	Prereqs     pgtype.Text
 	Coreqs      pgtype.Text
 	Antireqs    pgtype.Text
+	// Stored as JSON, or NULL if the requisites name no required courses
+	PrereqTree *requirement.Tree
+	CoreqTree  *requirement.Tree
 }
 
 type prereq struct {
@@ -0,0 +1,45 @@
+package course
+
+import (
+	"flow/common/requirement"
+	"flow/importer/uw/parts/course/requisite"
+)
+
+// requirementTree converts the syntax tree of requisites into a boolean tree
+// of course codes. Nodes other than courses, such as levels or programs,
+// are nil. They are dropped from conjunctions, but make disjunctions nil,
+// as we cannot tell whether such alternatives hold: in
+// "CS 135 or instructor consent", CS 135 is not actually required.
+func requirementTree(node requisite.Node) *requirement.Tree {
+	switch node := node.(type) {
+	case *requisite.Course:
+		return requirement.Course(node.Code)
+	case *requisite.AllOf:
+		children := make([]*requirement.Tree, len(node.Children))
+		for i, child := range node.Children {
+			children[i] = requirementTree(child)
+		}
+		return requirement.And(children...)
+	case *requisite.OneOf:
+		children := make([]*requirement.Tree, len(node.Children))
+		for i, child := range node.Children {
+			children[i] = requirementTree(child)
+			if children[i] == nil {
+				return nil
+			}
+		}
+		return requirement.Or(children...)
+	case *requisite.MinGrade:
+		// We do not know the grades of users, so only the courses are checked
+		return requirementTree(node.Child)
+	default:
+		return nil
+	}
+}
+
+// buildRequirementTree builds a boolean tree of the course codes in requisites
+// that were expanded by expandCourseCodes. The tree is nil if there are
+// no course codes, or none that are definitely required.
+func buildRequirementTree(expanded string) *requirement.Tree {
+	return requirementTree(requisite.Parse(expanded))
+}
@@ -0,0 +1,112 @@
+package exam
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"flow/common/util"
+	"flow/importer/uw/log"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Exams are almost always written by lecture sections,
+// so when the component is omitted, it is implied to be LEC.
+const defaultComponent = "LEC"
+
+// expandSections maps a section string of the form "LEC 001,003-005"
+// to the names of the sections it covers: "LEC 001", "LEC 003", etc.
+func expandSections(sections string) []string {
+	sections = strings.TrimSpace(sections)
+
+	end := 0
+	for end < len(sections) && util.IsUpperCase(sections[end]) {
+		end++
+	}
+	component := sections[:end]
+	if component == "" {
+		component = defaultComponent
+	}
+
+	var names []string
+	for _, number := range util.ExpandNumberRange(sections[end:]) {
+		names = append(names, fmt.Sprintf("%s %03d", component, number))
+	}
+	return names
+}
+
+func nonEmpty(value *string) bool {
+	return value != nil && strings.TrimSpace(*value) != ""
+}
+
+func convertAll(termExams map[int][]apiExam) []exam {
+	var exams []exam
+	for termId, apiExams := range termExams {
+		for _, apiExam := range apiExams {
+			converted, err := convertExam(&apiExam, termId)
+			if err != nil {
+				log.Warnf("failed to convert exam: %v", err)
+				continue
+			}
+			exams = append(exams, converted...)
+		}
+	}
+	return exams
+}
+
+func convertExam(apiExam *apiExam, termId int) ([]exam, error) {
+	courseCode := strings.ToLower(strings.ReplaceAll(apiExam.Course, " ", ""))
+	if courseCode == "" {
+		return nil, fmt.Errorf("exam missing course code")
+	}
+
+	sectionNames := expandSections(apiExam.Sections)
+	if len(sectionNames) == 0 {
+		return nil, fmt.Errorf("exam for %s has no sections: %q", courseCode, apiExam.Sections)
+	}
+
+	template := exam{
+		CourseCode: courseCode,
+		TermId:     termId,
+	}
+
+	if nonEmpty(apiExam.Location) {
+		template.Location = pgtype.Text{String: strings.TrimSpace(*apiExam.Location), Valid: true}
+	}
+	if nonEmpty(apiExam.Day) {
+		template.Day = pgtype.Text{String: strings.TrimSpace(*apiExam.Day), Valid: true}
+	}
+
+	// Exams with no date or no time are announced, but not yet scheduled.
+	if nonEmpty(apiExam.Date) && nonEmpty(apiExam.StartTime) && nonEmpty(apiExam.EndTime) {
+		date, err := time.Parse(util.ApiV3DateLayout, *apiExam.Date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert date: %w", err)
+		}
+		startSeconds, err := util.TimeStringToSeconds(*apiExam.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert time: %w", err)
+		}
+		endSeconds, err := util.TimeStringToSeconds(*apiExam.EndTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert time: %w", err)
+		}
+
+		template.Date = pgtype.Date{Time: date, Valid: true}
+		template.StartSeconds = pgtype.Int4{Int32: int32(startSeconds), Valid: true}
+		template.EndSeconds = pgtype.Int4{Int32: int32(endSeconds), Valid: true}
+		if !template.Day.Valid {
+			template.Day = pgtype.Text{String: date.Weekday().String(), Valid: true}
+		}
+	} else {
+		template.IsTba = true
+	}
+
+	exams := make([]exam, len(sectionNames))
+	for i, sectionName := range sectionNames {
+		exams[i] = template
+		exams[i].SectionName = sectionName
+	}
+	return exams, nil
+}
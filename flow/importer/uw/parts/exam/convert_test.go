@@ -0,0 +1,117 @@
+package exam
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestExpandSections(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"LEC 001", []string{"LEC 001"}},
+		{"LEC 001,003-005", []string{"LEC 001", "LEC 003", "LEC 004", "LEC 005"}},
+		{"001,002", []string{"LEC 001", "LEC 002"}},
+		{"TST 101", []string{"TST 101"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := expandSections(tt.input)
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestConvertExam(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []exam
+	}{
+		{
+			"scheduled",
+			`
+{
+	"course": "ECE 105",
+	"sections": "LEC 001-002",
+	"day": "Tuesday",
+	"date": "2019-12-10T00:00:00",
+	"startTime": "2019-12-10T09:00:00",
+	"endTime": "2019-12-10T11:30:00",
+	"location": "PAC 1, 2, 3"
+}
+			`,
+			[]exam{
+				{
+					CourseCode:   "ece105",
+					SectionName:  "LEC 001",
+					TermId:       1199,
+					Location:     pgtype.Text{String: "PAC 1, 2, 3", Valid: true},
+					StartSeconds: pgtype.Int4{Int32: 32400, Valid: true},
+					EndSeconds:   pgtype.Int4{Int32: 41400, Valid: true},
+					Date:         pgtype.Date{Time: time.Date(2019, 12, 10, 0, 0, 0, 0, time.UTC), Valid: true},
+					Day:          pgtype.Text{String: "Tuesday", Valid: true},
+				},
+				{
+					CourseCode:   "ece105",
+					SectionName:  "LEC 002",
+					TermId:       1199,
+					Location:     pgtype.Text{String: "PAC 1, 2, 3", Valid: true},
+					StartSeconds: pgtype.Int4{Int32: 32400, Valid: true},
+					EndSeconds:   pgtype.Int4{Int32: 41400, Valid: true},
+					Date:         pgtype.Date{Time: time.Date(2019, 12, 10, 0, 0, 0, 0, time.UTC), Valid: true},
+					Day:          pgtype.Text{String: "Tuesday", Valid: true},
+				},
+			},
+		},
+		{
+			"tba",
+			`
+{
+	"course": "CS 135",
+	"sections": "001",
+	"day": null,
+	"date": null,
+	"startTime": null,
+	"endTime": null,
+	"location": "TBA"
+}
+			`,
+			[]exam{
+				{
+					CourseCode:  "cs135",
+					SectionName: "LEC 001",
+					TermId:      1199,
+					Location:    pgtype.Text{String: "TBA", Valid: true},
+					IsTba:       true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input apiExam
+			err := json.Unmarshal([]byte(tt.input), &input)
+			if err != nil {
+				t.Fatalf("unmarshaling: %v", err)
+			}
+			got, err := convertExam(&input, 1199)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if !cmp.Equal(tt.want, got) {
+				t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
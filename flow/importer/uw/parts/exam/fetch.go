@@ -0,0 +1,33 @@
+package exam
+
+import (
+	"fmt"
+	"strings"
+
+	"flow/importer/uw/api"
+)
+
+func fetchAll(client *api.Client, termIds []int) (map[int][]apiExam, error) {
+	termExams := make(map[int][]apiExam)
+	for _, termId := range termIds {
+		exams, err := fetchByTerm(client, termId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch term %d: %w", termId, err)
+		}
+		termExams[termId] = exams
+	}
+	return termExams, nil
+}
+
+func fetchByTerm(client *api.Client, termId int) ([]apiExam, error) {
+	var exams []apiExam
+	endpoint := fmt.Sprintf("ExamSchedules/%d", termId)
+	err := client.Getv3(endpoint, &exams)
+
+	// Exam schedules are published late in the term, so most terms return a 404.
+	if err != nil && strings.Contains(err.Error(), "404") {
+		return nil, nil
+	}
+
+	return exams, err
+}
@@ -0,0 +1,34 @@
+package exam
+
+import (
+	"fmt"
+
+	"flow/common/state"
+	"flow/common/util"
+	"flow/importer/uw/api"
+	"flow/importer/uw/log"
+)
+
+// Exam schedules are only ever published for the current and next terms
+func requestedTermIds() []int {
+	return []int{util.CurrentTermId(), util.NextTermId()}
+}
+
+func ImportAll(state *state.State, client *api.Client) error {
+	log.StartImport("section_exam")
+
+	termExams, err := fetchAll(client, requestedTermIds())
+	if err != nil {
+		return fmt.Errorf("failed to fetch exams: %w", err)
+	}
+
+	exams := convertAll(termExams)
+
+	result, err := insertAll(state.Db, exams)
+	if err != nil {
+		return fmt.Errorf("failed to insert exams: %w", err)
+	}
+
+	log.EndImport("section_exam", result)
+	return nil
+}
@@ -0,0 +1,97 @@
+package exam
+
+import (
+	"fmt"
+
+	"flow/common/db"
+	"flow/common/util"
+	"flow/importer/uw/log"
+)
+
+const truncateExamQuery = `TRUNCATE work.section_exam_delta`
+
+const updateExamQuery = `
+UPDATE section_exam SET
+  location = delta.location,
+  start_seconds = delta.start_seconds,
+  end_seconds = delta.end_seconds,
+  date = delta.date,
+  day = delta.day,
+  is_tba = delta.is_tba
+FROM work.section_exam_delta delta
+  JOIN course c ON c.code = delta.course_code
+  JOIN course_section cs
+    ON cs.course_id = c.id
+   AND cs.section_name = delta.section_name
+   AND cs.term_id = delta.term_id
+WHERE section_exam.section_id = cs.id
+`
+
+const insertExamQuery = `
+INSERT INTO section_exam(
+  section_id, location, start_seconds, end_seconds, date, day, is_tba
+)
+SELECT
+  cs.id, d.location, d.start_seconds, d.end_seconds, d.date, d.day, d.is_tba
+FROM work.section_exam_delta d
+  -- must have a matching section
+  JOIN course c ON c.code = d.course_code
+  JOIN course_section cs
+    ON cs.course_id = c.id
+   AND cs.section_name = d.section_name
+   AND cs.term_id = d.term_id
+  LEFT JOIN section_exam se ON se.section_id = cs.id
+WHERE se.section_id IS NULL
+ON CONFLICT (section_id) DO NOTHING
+`
+
+func insertAll(conn *db.Conn, exams []exam) (*log.DbResult, error) {
+	var result log.DbResult
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return &result, fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(truncateExamQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to truncate work table: %w", err)
+	}
+
+	preparedExams := make([][]interface{}, len(exams))
+	for i, exam := range exams {
+		preparedExams[i] = util.AsSlice(exam)
+	}
+
+	_, err = tx.CopyFrom(
+		db.Identifier{"work", "section_exam_delta"},
+		util.Fields(exams),
+		preparedExams,
+	)
+	if err != nil {
+		return &result, fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	tag, err := tx.Exec(updateExamQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to apply update: %w", err)
+	}
+	result.Updated = int(tag.RowsAffected())
+
+	tag, err = tx.Exec(insertExamQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to insert: %w", err)
+	}
+	result.Inserted = int(tag.RowsAffected())
+
+	err = tx.Commit()
+	if err != nil {
+		return &result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Like with sections, we did not exclude any rows deliberately,
+	// so the remainder was rejected (most likely no matching section exists).
+	result.Rejected = len(exams) - result.Inserted - result.Updated
+	return &result, nil
+}
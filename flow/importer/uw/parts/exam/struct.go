@@ -0,0 +1,34 @@
+// Final exam schedules for sections offered in a given term.
+//
+// The ExamSchedules endpoint lists exams by course, and each entry
+// may cover several sections at once, e.g. "LEC 001,003-005".
+// Sections are not identified by class number, so exams are matched
+// against course_section by (course code, section name, term) instead.
+package exam
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+type exam struct {
+	CourseCode   string
+	SectionName  string
+	TermId       int
+	Location     pgtype.Text
+	StartSeconds pgtype.Int4
+	EndSeconds   pgtype.Int4
+	Date         pgtype.Date
+	Day          pgtype.Text
+	IsTba        bool
+}
+
+type apiExam struct {
+	// Of the form "CS 135"
+	Course string `json:"course"`
+	// Of the form "LEC 001,003-005" or simply "001,002"
+	Sections  string  `json:"sections"`
+	Day       *string `json:"day"`
+	Date      *string `json:"date"`
+	StartTime *string `json:"startTime"`
+	EndTime   *string `json:"endTime"`
+	Location  *string `json:"location"`
+	Notes     *string `json:"notes"`
+}
@@ -0,0 +1,36 @@
+package exam
+
+import (
+	"fmt"
+
+	"flow/common/state"
+	"flow/importer/uw/log"
+)
+
+// Exams of old sections are deleted along with the sections themselves,
+// but exams may also be dropped or merged while their sections remain.
+// Remove those of the terms given by $1 that were absent from the last import,
+// including all exams of a term whose schedule is no longer published (404).
+const deleteQuery = `
+DELETE FROM section_exam se
+USING course_section cs, course c
+WHERE cs.id = se.section_id
+  AND c.id = cs.course_id
+  AND cs.term_id = ANY($1)
+  AND NOT EXISTS (
+    SELECT FROM work.section_exam_delta d
+    WHERE d.course_code = c.code
+      AND d.section_name = cs.section_name
+      AND d.term_id = cs.term_id
+  )
+`
+
+func Vacuum(state *state.State) error {
+	log.StartVacuum("section_exam")
+	tag, err := state.Db.Exec(deleteQuery, requestedTermIds())
+	if err != nil {
+		return fmt.Errorf("deleting stale exams: %w", err)
+	}
+	log.EndVacuum("section_exam", int(tag.RowsAffected()))
+	return nil
+}
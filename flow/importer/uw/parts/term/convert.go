@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"flow/common/util"
@@ -16,6 +17,8 @@ import (
 const (
 	startEventName = "Classes begin"
 	endEventName   = "Classes end"
+	// Appears in event names such as "Reading week" and "Fall term reading days"
+	readingEventName = "reading"
 )
 
 func convertAll(events []apiEvent) ([]Term, error) {
@@ -68,3 +71,49 @@ func convertAll(events []apiEvent) ([]Term, error) {
 
 	return terms, nil
 }
+
+// convertHolidays collects the days without classes: university closures
+// and every day spanned by a reading break event.
+func convertHolidays(events []apiEvent, apiHolidays []apiHoliday) ([]holiday, error) {
+	seen := make(map[time.Time]bool)
+	var holidays []holiday
+
+	add := func(date time.Time, name string) {
+		if !seen[date] {
+			seen[date] = true
+			holidays = append(holidays, holiday{Date: date, Name: name})
+		}
+	}
+
+	for _, apiHoliday := range apiHolidays {
+		date, err := time.Parse(util.ApiV3DateLayout, apiHoliday.Date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+		add(date, apiHoliday.Name)
+	}
+
+	for _, event := range events {
+		if !strings.Contains(strings.ToLower(event.Name), readingEventName) {
+			continue
+		}
+		for _, detail := range event.Details {
+			startDate, err := time.Parse(util.ApiV3DateLayout, detail.Date)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse date: %w", err)
+			}
+			endDate := startDate
+			if detail.EndDate != nil && *detail.EndDate != "" {
+				endDate, err = time.Parse(util.ApiV3DateLayout, *detail.EndDate)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse date: %w", err)
+				}
+			}
+			for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+				add(date, event.Name)
+			}
+		}
+	}
+
+	return holidays, nil
+}
@@ -7,3 +7,9 @@ func fetchAll(client *api.Client) ([]apiEvent, error) {
 	err := client.Getv3("ImportantDates", &events)
 	return events, err
 }
+
+func fetchHolidays(client *api.Client) ([]apiHoliday, error) {
+	var holidays []apiHoliday
+	err := client.Getv3("HolidayDates/paidholidays", &holidays)
+	return holidays, err
+}
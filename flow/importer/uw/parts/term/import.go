@@ -27,5 +27,24 @@ func ImportAll(state *state.State, client *api.Client) error {
 	}
 
 	log.EndImport("term", result)
+
+	log.StartImport("holiday")
+
+	apiHolidays, err := fetchHolidays(client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch holidays: %w", err)
+	}
+
+	holidays, err := convertHolidays(events, apiHolidays)
+	if err != nil {
+		return fmt.Errorf("failed to convert holidays: %w", err)
+	}
+
+	result, err = insertAllHolidays(state.Db, holidays)
+	if err != nil {
+		return fmt.Errorf("failed to insert holidays: %w", err)
+	}
+
+	log.EndImport("holiday", result)
 	return nil
 }
@@ -74,3 +74,71 @@ func insertAll(conn *db.Conn, terms []Term) (*log.DbResult, error) {
 
 	return &result, nil
 }
+
+const truncateHolidayQuery = `TRUNCATE work.holiday_delta`
+
+const updateHolidayQuery = `
+UPDATE holiday SET
+  name = delta.name
+FROM work.holiday_delta delta
+WHERE holiday.date = delta.date
+  AND holiday.name != delta.name
+`
+
+const insertHolidayQuery = `
+INSERT INTO holiday(date, name)
+SELECT
+  d.date, d.name
+FROM work.holiday_delta d
+  LEFT JOIN holiday h ON h.date = d.date
+WHERE h.date IS NULL
+`
+
+func insertAllHolidays(conn *db.Conn, holidays []holiday) (*log.DbResult, error) {
+	var result log.DbResult
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return &result, fmt.Errorf("failed to open transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(truncateHolidayQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to truncate work table: %w", err)
+	}
+
+	preparedHolidays := make([][]interface{}, len(holidays))
+	for i, holiday := range holidays {
+		preparedHolidays[i] = util.AsSlice(holiday)
+	}
+
+	_, err = tx.CopyFrom(
+		db.Identifier{"work", "holiday_delta"},
+		util.Fields(holidays),
+		preparedHolidays,
+	)
+	if err != nil {
+		return &result, fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	tag, err := tx.Exec(updateHolidayQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to apply update: %w", err)
+	}
+	result.Updated = int(tag.RowsAffected())
+
+	tag, err = tx.Exec(insertHolidayQuery)
+	if err != nil {
+		return &result, fmt.Errorf("failed to insert: %w", err)
+	}
+	result.Inserted = int(tag.RowsAffected())
+
+	err = tx.Commit()
+	if err != nil {
+		return &result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Untouched = len(holidays) - result.Inserted - result.Updated
+	return &result, nil
+}
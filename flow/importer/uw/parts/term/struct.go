@@ -36,4 +36,20 @@ type apiEventDetail struct {
 	// In our case, start and end dates conincide,
 	// so EndDate is nil and this is simply the date.
 	Date string `json:"StartDate"`
+	// Only present for events spanning several days, such as reading breaks.
+	EndDate *string
+}
+
+// A day on which no classes are held.
+type holiday struct {
+	Date time.Time
+	Name string
+}
+
+// In UW API v3 spec, these are HolidayDates.
+// They are days on which the university is closed.
+type apiHoliday struct {
+	Name string
+	// Of the form "2020-02-17T00:00:00"
+	Date string
 }
@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"time"
 
 	"flow/common/state"
 	"flow/common/util"
@@ -10,13 +11,27 @@ import (
 
 const deleteQuery = `DELETE FROM term WHERE id < $1`
 
+const deleteHolidayQuery = `DELETE FROM holiday WHERE date < $1`
+
 func Vacuum(state *state.State) error {
+	// Retain only terms starting with the one before the previous one,
+	// which is the oldest term whose sections are imported
+	cutoffTermId := util.PreviousTermId(2)
+
 	log.StartVacuum("term")
-	// Retain only terms starting with the previous one
-	tag, err := state.Db.Exec(deleteQuery, util.PreviousTermId(2))
+	tag, err := state.Db.Exec(deleteQuery, cutoffTermId)
 	if err != nil {
 		return fmt.Errorf("database write failed: %w", err)
 	}
 	log.EndVacuum("term", int(tag.RowsAffected()))
+
+	log.StartVacuum("holiday")
+	// Likewise, retain only holidays from the first day of the month in which the cutoff term starts
+	termStart := time.Date(util.TermIdToYear(cutoffTermId), time.Month(cutoffTermId%10), 1, 0, 0, 0, 0, time.UTC)
+	tag, err = state.Db.Exec(deleteHolidayQuery, termStart)
+	if err != nil {
+		return fmt.Errorf("database write failed: %w", err)
+	}
+	log.EndVacuum("holiday", int(tag.RowsAffected()))
 	return nil
 }
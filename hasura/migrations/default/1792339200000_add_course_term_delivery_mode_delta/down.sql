@@ -0,0 +1 @@
+DROP TABLE IF EXISTS work.course_term_delivery_mode_delta;
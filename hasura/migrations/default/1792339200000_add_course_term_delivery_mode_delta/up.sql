@@ -0,0 +1,5 @@
+CREATE TABLE work.course_term_delivery_mode_delta(
+  course_code TEXT NOT NULL,
+  term_id INT NOT NULL,
+  delivery_mode TEXT NOT NULL
+);
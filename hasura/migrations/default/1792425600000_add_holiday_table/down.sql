@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS work.holiday_delta;
+DROP TABLE IF EXISTS holiday;
@@ -0,0 +1,11 @@
+-- Dates on which no classes are held: statutory holidays and reading breaks.
+-- These become EXDATEs of recurring events in webcal feeds.
+CREATE TABLE holiday (
+  date DATE PRIMARY KEY,
+  name TEXT NOT NULL
+);
+
+CREATE TABLE work.holiday_delta(
+  date DATE NOT NULL,
+  name TEXT NOT NULL
+);
@@ -0,0 +1,10 @@
+-- Tracks when the contents of each user's webcal feed last changed,
+-- so that polling calendar clients can be answered with 304 Not Modified.
+CREATE TABLE user_calendar (
+  user_id INT PRIMARY KEY
+    REFERENCES "user"(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+  etag TEXT NOT NULL,
+  modified_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
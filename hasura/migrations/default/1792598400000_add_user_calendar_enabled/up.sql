@@ -0,0 +1,2 @@
+-- Users may turn off their webcal feed entirely, in which case it is not served.
+ALTER TABLE "user" ADD COLUMN calendar_enabled BOOLEAN NOT NULL DEFAULT TRUE;
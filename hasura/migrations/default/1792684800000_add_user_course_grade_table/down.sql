@@ -0,0 +1 @@
+DROP TABLE IF EXISTS secret.user_course_grade;
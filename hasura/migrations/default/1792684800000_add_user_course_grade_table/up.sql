@@ -0,0 +1,18 @@
+-- Grades and credits from imported transcripts.
+-- These are only stored for users who opt in, and are never exposed through Hasura.
+CREATE TABLE secret.user_course_grade (
+  user_id INT NOT NULL
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE,
+  term_id INT NOT NULL,
+  -- Not a reference to course: transcripts contain courses that we do not know of
+  course_code TEXT NOT NULL,
+  title TEXT NOT NULL,
+  attempted_credits NUMERIC(4, 2) NOT NULL,
+  earned_credits NUMERIC(4, 2) NOT NULL,
+  -- Either numeric (e.g. 97) or not (e.g. CR), NULL for courses in progress
+  grade TEXT,
+  in_average BOOLEAN NOT NULL,
+  CONSTRAINT user_course_grade_unique UNIQUE(user_id, term_id, course_code)
+);
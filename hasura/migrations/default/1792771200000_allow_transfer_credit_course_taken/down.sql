@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION check_course_taken()
+RETURNS TRIGGER AS $$
+  BEGIN
+    IF NEW.legacy OR EXISTS(
+      SELECT
+      FROM user_course_taken
+      WHERE user_id = NEW.user_id
+      AND course_id = NEW.course_id
+    )
+    THEN RETURN NEW;
+    ELSE RAISE EXCEPTION 'course must have been taken';
+    END IF;
+  END
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS course_uniquely_transferred;
+
+DELETE FROM user_course_taken WHERE term_id IS NULL;
+ALTER TABLE user_course_taken ALTER COLUMN term_id SET NOT NULL;
@@ -0,0 +1,24 @@
+-- Transfer (AP/IB) credits are not taken in any term, so their term_id is NULL.
+ALTER TABLE user_course_taken ALTER COLUMN term_id DROP NOT NULL;
+
+-- course_uniquely_taken does not apply to NULL terms, as NULLs are distinct.
+CREATE UNIQUE INDEX course_uniquely_transferred
+ON user_course_taken(user_id, course_id)
+WHERE term_id IS NULL;
+
+-- Transfer credits count for prerequisites, but do not make a course reviewable.
+CREATE OR REPLACE FUNCTION check_course_taken()
+RETURNS TRIGGER AS $$
+  BEGIN
+    IF NEW.legacy OR EXISTS(
+      SELECT
+      FROM user_course_taken
+      WHERE user_id = NEW.user_id
+      AND course_id = NEW.course_id
+      AND term_id IS NOT NULL
+    )
+    THEN RETURN NEW;
+    ELSE RAISE EXCEPTION 'course must have been taken';
+    END IF;
+  END
+$$ LANGUAGE plpgsql;
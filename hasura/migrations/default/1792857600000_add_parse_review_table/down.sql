@@ -0,0 +1 @@
+DROP TABLE IF EXISTS secret.parse_review;
@@ -0,0 +1,15 @@
+-- Anonymized imports that we could not parse with confidence.
+-- These are only stored for users who consent, and are not linked to them,
+-- so that maintainers can turn them into test fixtures when formats change.
+CREATE TABLE secret.parse_review (
+  id SERIAL PRIMARY KEY,
+  kind TEXT NOT NULL
+    CONSTRAINT parse_review_kind CHECK (kind IN ('transcript', 'schedule')),
+  -- Text of the import with personal details replaced
+  input TEXT NOT NULL,
+  -- Zero if parsing failed altogether
+  confidence REAL NOT NULL,
+  unmatched_lines TEXT[] NOT NULL,
+  mismatches TEXT[] NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
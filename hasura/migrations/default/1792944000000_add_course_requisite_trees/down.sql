@@ -0,0 +1,5 @@
+ALTER TABLE work.course_delta DROP COLUMN IF EXISTS coreq_tree;
+ALTER TABLE work.course_delta DROP COLUMN IF EXISTS prereq_tree;
+
+ALTER TABLE course DROP COLUMN IF EXISTS coreq_tree;
+ALTER TABLE course DROP COLUMN IF EXISTS prereq_tree;
@@ -0,0 +1,7 @@
+-- Boolean trees of the courses named by prereqs and coreqs, built by the importer.
+-- Clauses that are not about courses, such as level or program restrictions, are left out.
+ALTER TABLE course ADD COLUMN prereq_tree JSONB;
+ALTER TABLE course ADD COLUMN coreq_tree JSONB;
+
+ALTER TABLE work.course_delta ADD COLUMN prereq_tree JSONB;
+ALTER TABLE work.course_delta ADD COLUMN coreq_tree JSONB;
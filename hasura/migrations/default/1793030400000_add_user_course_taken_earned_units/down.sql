@@ -0,0 +1 @@
+ALTER TABLE user_course_taken DROP COLUMN IF EXISTS earned_units;
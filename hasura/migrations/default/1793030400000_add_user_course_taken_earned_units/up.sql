@@ -0,0 +1,4 @@
+-- Units earned in courses from imported transcripts, e.g. 0.00 for failed or withdrawn courses.
+-- NULL where these are not known, such as for courses in progress or imported from schedules.
+-- Like grades, they are not exposed through Hasura.
+ALTER TABLE user_course_taken ADD COLUMN earned_units NUMERIC(4, 2);
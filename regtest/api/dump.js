@@ -9,6 +9,10 @@ function getDump() {
   return http.get(ENDPOINT);
 }
 
+function search(query) {
+  return http.get(ENDPOINT + "?" + query);
+}
+
 export default function(data) {
   group("dump", function() {
     check(getDump(), withLog({
@@ -26,4 +30,26 @@ export default function(data) {
       ),
     }));
   });
+
+  group("search", function() {
+    check(search("q=cs%20135&limit=5"), withLog({
+      "status": (r) => r.status == 200,
+      "keys": (r) => keysAre(r.json(), ["courses", "profs", "course_count", "prof_count"]),
+      "exact code first": (r) => r.json("courses.0.code") == "cs135",
+      "limit": (r) => r.json("courses").length <= 5 && r.json("profs").length <= 5,
+      "course keys": (r) => keysAre(
+        r.json("courses.0"),
+        ["id", "code", "name", "rating_count", "terms", "has_prereqs"]
+      ),
+    }));
+    check(search("q=cs&type=course&offset=20"), withLog({
+      "status": (r) => r.status == 200,
+      "no profs": (r) => r.json("profs").length == 0,
+      "prefix": (r) => r.json("courses").every((c) => c.code.startsWith("cs")),
+    }));
+    check(search("q=cs&delivery=hybrid"), withLog({
+      "status": (r) => r.status == 400,
+      "error": (r) => r.json("error") == "invalid_search_option",
+    }));
+  });
 }
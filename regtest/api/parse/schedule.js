@@ -6,10 +6,10 @@ import { withLog } from "/src/util.js";
 const ENDPOINT = API_URL + "/parse/schedule";
 const VALID_SCHEDULE = open("/src/fixtures/schedule.txt");
 
-function uploadSchedule(text, token) {
+function uploadSchedule(text, token, query = "") {
   const payload = {text}; 
   const headers = {"Authorization": `Bearer ${token}`};
-  return http.post(ENDPOINT, JSON.stringify(payload), {headers});
+  return http.post(ENDPOINT + query, JSON.stringify(payload), {headers});
 }
 
 export default function(data) { 
@@ -23,6 +23,7 @@ export default function(data) {
       check(uploadSchedule(VALID_SCHEDULE, data.email.token), withLog({
         "status": (r) => r.status == 200,
         "section count": (r) => r.json("sections_imported") == 9,
+        "term count": (r) => r.json("terms.#") == 1,
       }));
     });
     group("valid again", function() {
@@ -31,6 +32,13 @@ export default function(data) {
         "section count": (r) => r.json("sections_imported") == 9,
       }));
     });
+    group("preview unchanged", function() {
+      check(uploadSchedule(VALID_SCHEDULE, data.email.token, "?preview=true"), withLog({
+        "status": (r) => r.status == 200,
+        "nothing added": (r) => r.json("added") == null,
+        "nothing removed": (r) => r.json("removed") == null,
+      }));
+    });
     group("malformed", function() {
       check(uploadSchedule("", data.email.token), withLog({
         "status": (r) => r.status == 400,
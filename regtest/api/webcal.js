@@ -8,8 +8,17 @@ import { getUser } from "/src/graphql/user.js";
 const ENDPOINT = API_URL + "/calendar";
 const EXPECTED = open("/src/fixtures/calendar.txt");
 
-export function getCalendar(secret_id) {
-  return http.get(ENDPOINT + `/${secret_id}.ics`);
+export function getCalendar(secret_id, headers = {}) {
+  return http.get(ENDPOINT + `/${secret_id}.ics`, { headers: headers });
+}
+
+function getPublicCalendar(path, headers = {}) {
+  return http.get(ENDPOINT + `/${path}.ics`, { headers: headers });
+}
+
+function postCalendar(action, token) {
+  const headers = {"Authorization": `Bearer ${token}`};
+  return http.post(ENDPOINT + `/${action}`, null, {headers});
 }
 
 export default function(data) {
@@ -19,7 +28,8 @@ export default function(data) {
     const secretRegexp = RegExp(secretId, "g");
     group("valid", function() {
       check(getCalendar(secretId), withLog({
-        "status": (r) => r.status == 201,
+        "status": (r) => r.status == 200,
+        "validators": (r) => r.headers["Etag"] && r.headers["Last-Modified"],
         "MIME type": (r) => r.headers["Content-Type"].startsWith("text/calendar"),
         "correct body": (r) => {
           // DTSTAMP entries reflect time of query
@@ -29,10 +39,69 @@ export default function(data) {
         },
       }));
     });
+    group("not modified", function() {
+      const etag = getCalendar(secretId).headers["Etag"];
+      check(getCalendar(secretId, { "If-None-Match": etag }), withLog({
+        "status": (r) => r.status == 304,
+        "empty body": (r) => !r.body,
+      }));
+    });
     group("invalid", function() {
       check(getCalendar("notanid"), withLog({
         "status": (r) => r.status == 401,
       }));
     });
+    group("public", function() {
+      const section = getPublicCalendar("section/1201/6169");
+      check(section, withLog({
+        "section status": (r) => r.status == 200,
+        "section cache": (r) => r.headers["Cache-Control"].startsWith("public"),
+        "section body": (r) => r.body.includes("SUMMARY:CO255 - LEC 001"),
+      }));
+      check(getPublicCalendar("section/1201/6169", { "If-None-Match": section.headers["Etag"] }), withLog({
+        "section not modified": (r) => r.status == 304,
+      }));
+      check(getPublicCalendar("course/1201/co255"), withLog({
+        "course status": (r) => r.status == 200,
+        "course body": (r) => r.body.includes("SUMMARY:CO255 - LEC 001"),
+      }));
+      check(getPublicCalendar("section/1201/1"), withLog({
+        "unknown section": (r) => r.status == 404,
+      }));
+      check(getPublicCalendar("section/winter/6169"), withLog({
+        "malformed term": (r) => r.status == 400,
+      }));
+    });
+    group("disabled", function() {
+      check(postCalendar("disable", data.email.token), withLog({
+        "status": (r) => r.status == 200,
+      }));
+      check(getCalendar(secretId), withLog({
+        "status": (r) => r.status == 404,
+      }));
+      check(postCalendar("enable", data.email.token), withLog({
+        "status": (r) => r.status == 200,
+      }));
+      check(getCalendar(secretId), withLog({
+        "status": (r) => r.status == 200,
+      }));
+    });
+    group("rotated", function() {
+      check(postCalendar("rotate", ""), withLog({
+        "status": (r) => r.status == 401,
+      }));
+      const response = postCalendar("rotate", data.email.token);
+      const newSecretId = response.json("secret_id");
+      check(response, withLog({
+        "status": (r) => r.status == 200,
+        "new secret id": (r) => newSecretId && newSecretId != secretId,
+      }));
+      check(getCalendar(secretId), withLog({
+        "old URL": (r) => r.status == 401,
+      }));
+      check(getCalendar(newSecretId), withLog({
+        "new URL": (r) => r.status == 200,
+      }));
+    });
   });
 }